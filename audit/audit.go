@@ -0,0 +1,316 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const maxLineSize = 1024 * 1024
+
+// Entry represents an operation that changed, or attempted to change, the
+// applied policies
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Principal string    `json:"principal,omitempty"`
+	Client    string    `json:"client,omitempty"`
+	Action    string    `json:"action"`
+	Policies  []string  `json:"policies,omitempty"`
+	DryRun    bool      `json:"dry_run,omitempty"`
+	Status    int       `json:"status,omitempty"`
+	Outcome   string    `json:"outcome"`
+	PrevHash  string    `json:"prev_hash"`
+	Hash      string    `json:"hash"`
+	// Invalid holds a line of the log that is not an entry, such as one torn
+	// by a crash while it was written
+	Invalid string `json:"invalid,omitempty"`
+}
+
+// digest returns the hash of an entry, which covers every field but the hash
+// itself and therefore the hash of the previous entry too
+func (e Entry) digest() (string, error) {
+	e.Hash = ""
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Log appends entries as JSON lines to a file, chaining each entry to the
+// previous one by hash so that altered or removed entries are detected. The
+// file is rotated to file.1, file.2 and so on once it reaches its maximum
+// size, and the chain continues across rotated files.
+type Log struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	mu       sync.Mutex
+	f        *os.File
+	size     int64
+	lastHash string
+}
+
+// New opens the audit log at path, continuing the hash chain of its last
+// valid entry. maxFiles is the number of rotated files kept besides path.
+func New(path string, maxSize int64, maxFiles int) (*Log, error) {
+	l := &Log{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	for _, p := range []string{path, l.rotated(1)} {
+		entries, err := readFile(p)
+		if err != nil {
+			return nil, err
+		}
+		for i := len(entries) - 1; i >= 0 && l.lastHash == ""; i-- {
+			if entries[i].Invalid == "" {
+				l.lastHash = entries[i].Hash
+			}
+		}
+		if l.lastHash != "" {
+			break
+		}
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Log) rotated(n int) string {
+	return l.path + "." + strconv.Itoa(n)
+}
+
+// open opens the log file for appending. A torn last line is terminated so
+// that the entries that follow start on their own line.
+func (l *Log) open() error {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	size := fi.Size()
+	if size > 0 {
+		last := make([]byte, 1)
+		if _, err = f.ReadAt(last, size-1); err == nil && last[0] != '\n' {
+			_, err = f.Write([]byte{'\n'})
+			size++
+		}
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	l.f = f
+	l.size = size
+	return nil
+}
+
+// rotate shifts the rotated files by one, dropping the oldest, and starts a
+// new file
+func (l *Log) rotate() error {
+	if err := l.f.Close(); err != nil {
+		return err
+	}
+	if err := os.Remove(l.rotated(l.maxFiles)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for n := l.maxFiles - 1; n >= 1; n-- {
+		if err := os.Rename(l.rotated(n), l.rotated(n+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if l.maxFiles > 0 {
+		if err := os.Rename(l.path, l.rotated(1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(l.path); err != nil {
+		return err
+	}
+	return l.open()
+}
+
+// Record chains an entry to the previous one and appends it to the log
+func (l *Log) Record(e Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+	e.PrevHash = l.lastHash
+	hash, err := e.digest()
+	if err != nil {
+		return err
+	}
+	e.Hash = hash
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	if l.maxSize > 0 && l.size > 0 && l.size+int64(len(b)) > l.maxSize {
+		if err = l.rotate(); err != nil {
+			return err
+		}
+	}
+	// A failed write is truncated so that it does not tear the log
+	if _, err = l.f.Write(b); err != nil {
+		return errors.Join(err, l.f.Truncate(l.size))
+	}
+	l.size += int64(len(b))
+	l.lastHash = e.Hash
+	return nil
+}
+
+// Entries returns the entries of the rotated files and of the log, oldest
+// first
+func (l *Log) Entries() ([]Entry, error) {
+	return l.Tail(0, nil)
+}
+
+// Tail returns the entries of the newest files of the log holding at least n
+// entries matching match, or of every file when n is not positive, oldest
+// first. The entries are contiguous so that their chain can be verified. The
+// files are read from the newest one without blocking Record.
+func (l *Log) Tail(n int, match func(Entry) bool) ([]Entry, error) {
+	files, err := l.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, f := range files {
+			_ = f.f.Close()
+		}
+	}()
+
+	var entries []Entry
+	found := 0
+	for _, f := range files {
+		e, err := readEntries(f.r, f.name)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(e, entries...)
+		for _, entry := range e {
+			if match == nil || match(entry) {
+				found++
+			}
+		}
+		if n > 0 && found >= n {
+			break
+		}
+	}
+	return entries, nil
+}
+
+// snapshotFile is a log file opened for reading, limited to the entries
+// written when it was opened
+type snapshotFile struct {
+	f    *os.File
+	r    io.Reader
+	name string
+}
+
+// snapshot opens the log and its rotated files, newest first. Open files keep
+// their content when they are rotated, so they can be read without holding
+// the lock.
+func (l *Log) snapshot() ([]snapshotFile, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var files []snapshotFile
+	for n := 0; n <= l.maxFiles; n++ {
+		p := l.path
+		if n > 0 {
+			p = l.rotated(n)
+		}
+		f, err := os.Open(p)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			for _, f := range files {
+				_ = f.f.Close()
+			}
+			return nil, err
+		}
+		var r io.Reader = f
+		if n == 0 {
+			r = io.LimitReader(f, l.size)
+		}
+		files = append(files, snapshotFile{f: f, r: r, name: p})
+	}
+	return files, nil
+}
+
+// Close closes the log file
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
+
+func readFile(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return readEntries(f, path)
+}
+
+// readEntries reads the entries of a log file. Lines that are not entries
+// are returned as invalid entries, which Verify reports.
+func readEntries(r io.Reader, path string) ([]Entry, error) {
+	var entries []Entry
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for s.Scan() {
+		var e Entry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			e = Entry{Invalid: s.Text()}
+		}
+		entries = append(entries, e)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// Verify checks that every entry matches its hash and is chained to the entry
+// before it. The first entry is trusted to follow entries dropped by rotation.
+func Verify(entries []Entry) error {
+	for i, e := range entries {
+		if e.Invalid != "" {
+			return fmt.Errorf("entry %d is incomplete or not valid JSON", i+1)
+		}
+		hash, err := e.digest()
+		if err != nil {
+			return err
+		}
+		if hash != e.Hash {
+			return fmt.Errorf("entry %d of %s was altered", i+1, e.Time.Format(time.RFC3339Nano))
+		}
+		if i > 0 && e.PrevHash != entries[i-1].Hash {
+			return fmt.Errorf("entry %d of %s does not follow the previous entry", i+1, e.Time.Format(time.RFC3339Nano))
+		}
+	}
+	return nil
+}
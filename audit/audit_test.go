@@ -0,0 +1,161 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestLog(t *testing.T, path string, maxSize int64, maxFiles int) *Log {
+	t.Helper()
+	l, err := New(path, maxSize, maxFiles)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	return l
+}
+
+func record(t *testing.T, l *Log, action string, policy string) {
+	t.Helper()
+	if err := l.Record(Entry{Principal: "ci", Action: action, Policies: []string{policy}, Outcome: "applied"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Entries are chained by hash, also across a reopened log.
+func TestRecordChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l := newTestLog(t, path, 0, 0)
+	record(t, l, "create", "p1")
+	record(t, l, "update", "p1")
+	if err := l.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l = newTestLog(t, path, 0, 0)
+	record(t, l, "delete", "p1")
+	entries, err := l.Entries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 3 || entries[0].PrevHash != "" || entries[2].Action != "delete" {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+	if err = Verify(entries); err != nil {
+		t.Errorf("expected a valid chain, got %v", err)
+	}
+}
+
+// The log is rotated at its maximum size, keeping maxFiles rotated files.
+func TestRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l := newTestLog(t, path, 300, 2)
+	for i := 0; i < 10; i++ {
+		record(t, l, "create", "p1")
+	}
+	if _, err := os.Stat(path + ".2"); err != nil {
+		t.Errorf("expected a second rotated file: %v", err)
+	}
+	if _, err := os.Stat(path + ".3"); err == nil {
+		t.Errorf("expected at most two rotated files")
+	}
+	entries, err := l.Entries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) == 0 || len(entries) >= 10 {
+		t.Errorf("expected the oldest entries to be dropped, got %d entries", len(entries))
+	}
+	if err = Verify(entries); err != nil {
+		t.Errorf("expected the chain to continue across rotated files, got %v", err)
+	}
+}
+
+// Verify detects altered and removed entries.
+func TestVerifyTampering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l := newTestLog(t, path, 0, 0)
+	for _, policy := range []string{"p1", "p2", "p3"} {
+		record(t, l, "create", policy)
+	}
+	entries, err := l.Entries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	altered := append([]Entry(nil), entries...)
+	altered[1].Principal = "someone-else"
+	if err = Verify(altered); err == nil || !strings.Contains(err.Error(), "altered") {
+		t.Errorf("expected an altered entry, got %v", err)
+	}
+	removed := []Entry{entries[0], entries[2]}
+	if err = Verify(removed); err == nil || !strings.Contains(err.Error(), "does not follow") {
+		t.Errorf("expected a broken chain, got %v", err)
+	}
+}
+
+// Tail only reads the newest files holding enough matching entries.
+func TestTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l := newTestLog(t, path, 300, 10)
+	record(t, l, "create", "p2")
+	for i := 0; i < 10; i++ {
+		record(t, l, "update", "p1")
+	}
+	all, err := l.Entries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries, err := l.Tail(1, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) == 0 || len(entries) >= len(all) || entries[len(entries)-1].Hash != all[len(all)-1].Hash {
+		t.Errorf("expected the entries of the newest file only, got %d of %d", len(entries), len(all))
+	}
+	if err = Verify(entries); err != nil {
+		t.Errorf("expected contiguous entries, got %v", err)
+	}
+
+	entries, err = l.Tail(1, func(e Entry) bool { return e.Policies[0] == "p2" })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != len(all) {
+		t.Errorf("expected every file to be read to find p2, got %d of %d", len(entries), len(all))
+	}
+}
+
+// A line torn by a crash does not prevent opening the log and is reported by Verify.
+func TestTornLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l := newTestLog(t, path, 0, 0)
+	record(t, l, "create", "p1")
+	if err := l.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = f.WriteString(`{"time":"2024-01-01T00:00:00Z","act`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = f.Close()
+
+	l = newTestLog(t, path, 0, 0)
+	record(t, l, "update", "p1")
+	entries, err := l.Entries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 3 || entries[1].Invalid == "" || entries[2].Action != "update" || entries[2].PrevHash != entries[0].Hash {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+	if err = Verify(entries); err == nil || !strings.Contains(err.Error(), "entry 2 is incomplete") {
+		t.Errorf("expected the torn entry to be reported, got %v", err)
+	}
+}
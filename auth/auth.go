@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/netboxlabs/opentelemetry-infinity/config"
+)
+
+// ErrNoCredentials is returned when a request carries no credentials an
+// authenticator can verify
+var ErrNoCredentials = errors.New("no credentials")
+
+// ErrInvalidCredentials is returned when the credentials of a request are not
+// valid
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Principal represents an authenticated client
+type Principal struct {
+	Name   string
+	Method string
+}
+
+// Authenticator verifies the credentials of a request
+type Authenticator interface {
+	// Authenticate returns the principal of a request
+	Authenticate(r *http.Request) (Principal, error)
+	// Challenge returns the WWW-Authenticate challenge of the authenticator
+	Challenge() string
+}
+
+// Chain tries each authenticator in turn until one of them accepts the request
+type Chain []Authenticator
+
+// Authenticate returns the principal of the first authenticator accepting the
+// request. If none does, the first error other than ErrNoCredentials is
+// returned.
+func (ch Chain) Authenticate(r *http.Request) (Principal, error) {
+	var failure error
+	for _, a := range ch {
+		p, err := a.Authenticate(r)
+		if err == nil {
+			return p, nil
+		}
+		if failure == nil && !errors.Is(err, ErrNoCredentials) {
+			failure = err
+		}
+	}
+	if failure != nil {
+		return Principal{}, failure
+	}
+	return Principal{}, ErrNoCredentials
+}
+
+// Challenges returns the WWW-Authenticate challenges of the authenticators
+func (ch Chain) Challenges() []string {
+	challenges := make([]string, 0, len(ch))
+	seen := make(map[string]bool)
+	for _, a := range ch {
+		if c := a.Challenge(); !seen[c] {
+			seen[c] = true
+			challenges = append(challenges, c)
+		}
+	}
+	return challenges
+}
+
+// New creates the authenticators enabled in the configuration. An empty chain
+// is returned when authentication is disabled.
+func New(c *config.Config) (Chain, error) {
+	var ch Chain
+	if c.AuthTokensFile != "" {
+		a, err := NewTokenAuthenticator(c.AuthTokensFile)
+		if err != nil {
+			return nil, err
+		}
+		ch = append(ch, a)
+	}
+	if c.AuthJWKSFile != "" {
+		a, err := NewJWTAuthenticator(c.AuthJWKSFile, c.AuthJWTIssuer, c.AuthJWTAudience)
+		if err != nil {
+			return nil, err
+		}
+		ch = append(ch, a)
+	}
+	if c.AuthBasicFile != "" {
+		a, err := NewBasicAuthenticator(c.AuthBasicFile)
+		if err != nil {
+			return nil, err
+		}
+		ch = append(ch, a)
+	}
+	return ch, nil
+}
+
+// readCredentials reads a file of name:secret lines. Empty lines and lines
+// starting with # are ignored.
+func readCredentials(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	creds := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, secret, ok := strings.Cut(line, ":")
+		if !ok || name == "" || secret == "" {
+			return nil, fmt.Errorf("%s:%d: expected name:secret", path, n)
+		}
+		creds[name] = secret
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+// bearerToken returns the bearer token of a request
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
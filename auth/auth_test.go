@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/netboxlabs/opentelemetry-infinity/config"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "credentials")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return path
+}
+
+func newRequest(authorization string) *http.Request {
+	r, _ := http.NewRequest("GET", "/api/v1/status", nil)
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+	return r
+}
+
+// TokenAuthenticator maps known bearer tokens to their principal.
+func TestTokenAuthenticator(t *testing.T) {
+	a, err := NewTokenAuthenticator(writeFile(t, "# deploy tokens\nci:s3cr3t\n\nops:t0k3n:with:colons\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		header string
+		name   string
+		err    error
+	}{
+		{"Bearer s3cr3t", "ci", nil},
+		{"bearer t0k3n:with:colons", "ops", nil},
+		{"Bearer wrong", "", ErrInvalidCredentials},
+		{"Basic Y2k6czNjcjN0", "", ErrNoCredentials},
+		{"", "", ErrNoCredentials},
+	}
+	for _, tc := range cases {
+		p, err := a.Authenticate(newRequest(tc.header))
+		if !errors.Is(err, tc.err) || p.Name != tc.name {
+			t.Errorf("%q: expected %q, %v, got %q, %v", tc.header, tc.name, tc.err, p.Name, err)
+		}
+	}
+}
+
+// BasicAuthenticator checks basic credentials against bcrypt hashes.
+func TestBasicAuthenticator(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("pa55"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a, err := NewBasicAuthenticator(writeFile(t, "admin:"+string(hash)+"\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r := newRequest("")
+	r.SetBasicAuth("admin", "pa55")
+	if p, err := a.Authenticate(r); err != nil || p.Name != "admin" || p.Method != "basic" {
+		t.Errorf("expected admin, got %+v, %v", p, err)
+	}
+	for _, user := range []string{"admin", "nobody"} {
+		r = newRequest("")
+		r.SetBasicAuth(user, "wrong")
+		if _, err = a.Authenticate(r); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("%s: expected invalid credentials, got %v", user, err)
+		}
+	}
+
+	if _, err = NewBasicAuthenticator(writeFile(t, "admin:plaintext\n")); err == nil {
+		t.Errorf("expected an error for a password that is not a bcrypt hash")
+	}
+	if _, err = NewBasicAuthenticator(writeFile(t, "admin\n")); err == nil {
+		t.Errorf("expected an error for a line without hash")
+	}
+}
+
+// Chain accepts a request accepted by any authenticator and reports the most relevant failure.
+func TestChain(t *testing.T) {
+	ch, err := New(&config.Config{})
+	if err != nil || len(ch) != 0 {
+		t.Fatalf("expected authentication to be disabled, got %v, %v", ch, err)
+	}
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("pa55"), bcrypt.MinCost)
+	ch, err = New(&config.Config{AuthTokensFile: writeFile(t, "ci:s3cr3t\n"), AuthBasicFile: writeFile(t, "admin:"+string(hash)+"\n")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p, err := ch.Authenticate(newRequest("Bearer s3cr3t")); err != nil || p.Name != "ci" {
+		t.Errorf("expected ci, got %+v, %v", p, err)
+	}
+	r := newRequest("")
+	r.SetBasicAuth("admin", "pa55")
+	if p, err := ch.Authenticate(r); err != nil || p.Name != "admin" {
+		t.Errorf("expected admin, got %+v, %v", p, err)
+	}
+	if _, err = ch.Authenticate(newRequest("Bearer wrong")); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected invalid credentials, got %v", err)
+	}
+	if _, err = ch.Authenticate(newRequest("")); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("expected no credentials, got %v", err)
+	}
+	if got := ch.Challenges(); len(got) != 2 {
+		t.Errorf("expected a bearer and a basic challenge, got %v", got)
+	}
+
+	if _, err = New(&config.Config{AuthTokensFile: "/nonexistent/tokens"}); err == nil {
+		t.Errorf("expected an error for a missing tokens file")
+	}
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Action represents a kind of operation on policies
+type Action int
+
+const (
+	// ActionRead covers reading the status, policies, revisions and logs
+	ActionRead Action = iota
+	// ActionOperate covers operating running policies, such as resetting them
+	ActionOperate
+	// ActionWrite covers creating, updating, rolling back and deleting policies
+	ActionWrite
+)
+
+var actionNames = map[Action]string{
+	ActionRead:    "read",
+	ActionOperate: "operate",
+	ActionWrite:   "write",
+}
+
+func (a Action) String() string {
+	return actionNames[a]
+}
+
+// roleActions maps each role to the most privileged action it allows. Roles
+// allow every less privileged action as well.
+var roleActions = map[string]Action{
+	"viewer":   ActionRead,
+	"operator": ActionOperate,
+	"admin":    ActionWrite,
+}
+
+// Scope restricts a binding to the policies whose name starts with one of the
+// prefixes and that hold all of the labels. Empty fields match every policy.
+type Scope struct {
+	Prefixes []string          `yaml:"prefixes,omitempty"`
+	Labels   map[string]string `yaml:"labels,omitempty"`
+}
+
+// unscoped reports whether the scope matches every policy
+func (s *Scope) unscoped() bool {
+	return s == nil || (len(s.Prefixes) == 0 && len(s.Labels) == 0)
+}
+
+func (s *Scope) matches(t Target) bool {
+	if s == nil {
+		return true
+	}
+	if len(s.Prefixes) > 0 {
+		matched := false
+		for _, prefix := range s.Prefixes {
+			if strings.HasPrefix(t.Name, prefix) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for k, v := range s.Labels {
+		if t.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Binding grants a role to principals, optionally on a scope of policies.
+// The principal * matches every authenticated principal.
+type Binding struct {
+	Principals []string `yaml:"principals"`
+	Role       string   `yaml:"role"`
+	Policies   *Scope   `yaml:"policies,omitempty"`
+}
+
+func (b Binding) appliesTo(principal string) bool {
+	for _, p := range b.Principals {
+		if p == "*" || p == principal {
+			return true
+		}
+	}
+	return false
+}
+
+// Target represents a policy an action is authorized on
+type Target struct {
+	Name   string
+	Labels map[string]string
+}
+
+// Authorizer decides which actions principals may perform from role bindings
+type Authorizer struct {
+	bindings []Binding
+}
+
+// NewAuthorizer loads the role bindings of a YAML file
+func NewAuthorizer(path string) (*Authorizer, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Bindings []Binding `yaml:"bindings"`
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err = dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, binding := range file.Bindings {
+		if _, ok := roleActions[binding.Role]; !ok {
+			return nil, fmt.Errorf("%s: binding %d: unknown role %q, expected viewer, operator or admin", path, i+1, binding.Role)
+		}
+		if len(binding.Principals) == 0 {
+			return nil, fmt.Errorf("%s: binding %d: no principals", path, i+1)
+		}
+	}
+	return &Authorizer{bindings: file.Bindings}, nil
+}
+
+// Allowed reports whether a principal may perform an action on all of the
+// targets. Without targets, it reports whether the principal may perform the
+// action on any policy.
+func (a *Authorizer) Allowed(principal string, action Action, targets ...Target) bool {
+	var bindings []Binding
+	for _, b := range a.bindings {
+		if b.appliesTo(principal) && roleActions[b.Role] >= action {
+			bindings = append(bindings, b)
+		}
+	}
+	if len(targets) == 0 {
+		return len(bindings) > 0
+	}
+	for _, t := range targets {
+		allowed := false
+		for _, b := range bindings {
+			if b.Policies.matches(t) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// AllowedUnscoped reports whether a principal may perform an action on every
+// policy, through a binding that is not scoped to some of them
+func (a *Authorizer) AllowedUnscoped(principal string, action Action) bool {
+	for _, b := range a.bindings {
+		if b.appliesTo(principal) && roleActions[b.Role] >= action && b.Policies.unscoped() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package auth
+
+import "testing"
+
+const testBindings = `bindings:
+  - principals: ["*"]
+    role: viewer
+  - principals: [ops]
+    role: operator
+    policies:
+      prefixes: [team-a-, team-b-]
+  - principals: [alice]
+    role: admin
+    policies:
+      labels:
+        team: a
+`
+
+// Roles allow their own and less privileged actions on the policies in scope.
+func TestAuthorizer(t *testing.T) {
+	a, err := NewAuthorizer(writeFile(t, testBindings))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	teamA := Target{Name: "team-a-metrics", Labels: map[string]string{"team": "a"}}
+	other := Target{Name: "other", Labels: map[string]string{"team": "b"}}
+	cases := []struct {
+		principal string
+		action    Action
+		targets   []Target
+		allowed   bool
+	}{
+		{"anyone", ActionRead, nil, true},
+		{"anyone", ActionRead, []Target{other}, true},
+		{"anyone", ActionOperate, nil, false},
+		{"ops", ActionOperate, []Target{teamA}, true},
+		{"ops", ActionOperate, []Target{other}, false},
+		{"ops", ActionWrite, []Target{teamA}, false},
+		{"alice", ActionWrite, nil, true},
+		{"alice", ActionWrite, []Target{teamA}, true},
+		{"alice", ActionOperate, []Target{teamA}, true},
+		{"alice", ActionWrite, []Target{teamA, other}, false},
+		{"alice", ActionWrite, []Target{{Name: "new"}}, false},
+	}
+	for _, tc := range cases {
+		if got := a.Allowed(tc.principal, tc.action, tc.targets...); got != tc.allowed {
+			t.Errorf("%s %s %v: expected %v, got %v", tc.principal, tc.action, tc.targets, tc.allowed, got)
+		}
+	}
+}
+
+// Only bindings without scope allow actions on every policy.
+func TestAllowedUnscoped(t *testing.T) {
+	a, err := NewAuthorizer(writeFile(t, testBindings+"  - principals: [bob]\n    role: admin\n    policies: {}\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cases := []struct {
+		principal string
+		action    Action
+		allowed   bool
+	}{
+		{"anyone", ActionRead, true},
+		{"anyone", ActionWrite, false},
+		{"ops", ActionOperate, false},
+		{"alice", ActionWrite, false},
+		{"alice", ActionRead, true},
+		{"bob", ActionWrite, true},
+	}
+	for _, tc := range cases {
+		if got := a.AllowedUnscoped(tc.principal, tc.action); got != tc.allowed {
+			t.Errorf("%s %s: expected %v, got %v", tc.principal, tc.action, tc.allowed, got)
+		}
+	}
+}
+
+// NewAuthorizer rejects unknown roles, bindings without principals and unknown fields.
+func TestNewAuthorizerInvalid(t *testing.T) {
+	for _, content := range []string{
+		"bindings:\n  - principals: [ci]\n    role: root\n",
+		"bindings:\n  - role: viewer\n",
+		"bindings:\n  - principals: [ci]\n    role: viewer\n    scope: {}\n",
+	} {
+		if _, err := NewAuthorizer(writeFile(t, content)); err == nil {
+			t.Errorf("%q: expected an error", content)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyHash is compared against for unknown users, so that they take as long
+// to reject as known users with a wrong password
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("otlpinf"), bcrypt.DefaultCost)
+	return hash
+})
+
+// BasicAuthenticator accepts HTTP basic credentials checked against bcrypt
+// hashes
+type BasicAuthenticator struct {
+	hashes map[string][]byte
+}
+
+// NewBasicAuthenticator loads the users of an htpasswd file holding one
+// user:bcrypt-hash line per user
+func NewBasicAuthenticator(path string) (*BasicAuthenticator, error) {
+	creds, err := readCredentials(path)
+	if err != nil {
+		return nil, err
+	}
+	a := &BasicAuthenticator{hashes: make(map[string][]byte, len(creds))}
+	for user, hash := range creds {
+		if _, err = bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("%s: user %q: %w", path, user, err)
+		}
+		a.hashes[user] = []byte(hash)
+	}
+	return a, nil
+}
+
+// Authenticate returns the user of the basic credentials of a request
+func (a *BasicAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return Principal{}, ErrNoCredentials
+	}
+	hash, known := a.hashes[user]
+	if !known {
+		hash = dummyHash()
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || !known {
+		return Principal{}, ErrInvalidCredentials
+	}
+	return Principal{Name: user, Method: "basic"}, nil
+}
+
+// Challenge returns the basic challenge
+func (a *BasicAuthenticator) Challenge() string {
+	return `Basic realm="otlpinf"`
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func encodeBigInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func writeJWKS(t *testing.T, keys ...map[string]string) string {
+	t.Helper()
+	b, err := json.Marshal(map[string]interface{}{"keys": keys})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return writeFile(t, string(b))
+}
+
+// JWTAuthenticator accepts JWTs signed by the keys of the JWKS and holding the required claims.
+func TestJWTAuthenticator(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ecPub, err := ecKey.PublicKey.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	path := writeJWKS(t,
+		map[string]string{"kty": "RSA", "kid": "rsa", "use": "sig", "n": encodeBigInt(rsaKey.N), "e": encodeBigInt(big.NewInt(int64(rsaKey.E)))},
+		map[string]string{"kty": "EC", "kid": "ec", "crv": "P-256",
+			"x": base64.RawURLEncoding.EncodeToString(ecPub[1:33]), "y": base64.RawURLEncoding.EncodeToString(ecPub[33:])},
+	)
+	a, err := NewJWTAuthenticator(path, "https://idp.example.com", "otlpinf")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sign := func(method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = kid
+		s, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return s
+	}
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{"sub": "ci", "iss": "https://idp.example.com", "aud": "otlpinf", "exp": time.Now().Add(time.Hour).Unix()}
+	}
+
+	for _, token := range []string{
+		sign(jwt.SigningMethodRS256, "rsa", rsaKey, valid()),
+		sign(jwt.SigningMethodES256, "ec", ecKey, valid()),
+	} {
+		if p, err := a.Authenticate(newRequest("Bearer " + token)); err != nil || p.Name != "ci" || p.Method != "jwt" {
+			t.Errorf("expected ci, got %+v, %v", p, err)
+		}
+	}
+
+	expired := valid()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	otherAudience := valid()
+	otherAudience["aud"] = "other"
+	noExpiry := valid()
+	delete(noExpiry, "exp")
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	for name, token := range map[string]string{
+		"expired":        sign(jwt.SigningMethodRS256, "rsa", rsaKey, expired),
+		"other audience": sign(jwt.SigningMethodRS256, "rsa", rsaKey, otherAudience),
+		"no expiry":      sign(jwt.SigningMethodRS256, "rsa", rsaKey, noExpiry),
+		"other key":      sign(jwt.SigningMethodRS256, "rsa", otherKey, valid()),
+		"unknown kid":    sign(jwt.SigningMethodRS256, "missing", rsaKey, valid()),
+		"hmac":           sign(jwt.SigningMethodHS256, "rsa", []byte("secret"), valid()),
+		"not a jwt":      "s3cr3t",
+	} {
+		if _, err = a.Authenticate(newRequest("Bearer " + token)); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("%s: expected invalid credentials, got %v", name, err)
+		}
+	}
+}
+
+// parseJWKS rejects sets without usable signature keys.
+func TestParseJWKSErrors(t *testing.T) {
+	for _, jwks := range []string{
+		`{`,
+		`{"keys": []}`,
+		`{"keys": [{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"}]}`,
+		`{"keys": [{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"}]}`,
+		`{"keys": [{"kty": "EC", "kid": "ec", "crv": "P-256", "x": "AQAB", "y": "AQAB"}]}`,
+	} {
+		if _, err := parseJWKS([]byte(jwks)); err == nil {
+			t.Errorf("%s: expected an error", jwks)
+		}
+	}
+}
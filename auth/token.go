@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+)
+
+// TokenAuthenticator accepts static bearer tokens
+type TokenAuthenticator struct {
+	tokens map[string][sha256.Size]byte
+}
+
+// NewTokenAuthenticator loads the tokens of a file holding one principal:token
+// line per token
+func NewTokenAuthenticator(path string) (*TokenAuthenticator, error) {
+	creds, err := readCredentials(path)
+	if err != nil {
+		return nil, err
+	}
+	a := &TokenAuthenticator{tokens: make(map[string][sha256.Size]byte, len(creds))}
+	for name, token := range creds {
+		a.tokens[name] = sha256.Sum256([]byte(token))
+	}
+	return a, nil
+}
+
+// Authenticate returns the principal of the bearer token of a request. Every
+// token is compared in constant time so that the comparison does not reveal
+// the tokens.
+func (a *TokenAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return Principal{}, ErrNoCredentials
+	}
+	sum := sha256.Sum256([]byte(token))
+	var name string
+	for n, t := range a.tokens {
+		if subtle.ConstantTimeCompare(sum[:], t[:]) == 1 {
+			name = n
+		}
+	}
+	if name == "" {
+		return Principal{}, ErrInvalidCredentials
+	}
+	return Principal{Name: name, Method: "token"}, nil
+}
+
+// Challenge returns the bearer challenge
+func (a *TokenAuthenticator) Challenge() string {
+	return `Bearer realm="otlpinf"`
+}
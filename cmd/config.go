@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/netboxlabs/opentelemetry-infinity/config"
+)
+
+const (
+	configFlag = "config"
+	envPrefix  = "OTLPINF_"
+	keyPrefix  = "otlpinf_"
+)
+
+// flagKeys maps the flags whose configuration key is not the flag name
+// prefixed with otlpinf_
+var flagKeys = map[string]string{
+	"set":           "set",
+	"feature_gates": "feature_gates",
+	"distribution":  "otlpinf_distributions",
+}
+
+// configKey returns the configuration key of a flag, which is also used in
+// the configuration file
+func configKey(flag string) string {
+	if key, ok := flagKeys[flag]; ok {
+		return key
+	}
+	return keyPrefix + flag
+}
+
+// envVar returns the environment variable of a configuration key
+func envVar(key string) string {
+	return envPrefix + strings.ToUpper(strings.TrimPrefix(key, keyPrefix))
+}
+
+// loadConfig builds the otlpinf configuration from, in order of precedence,
+// the flags set on the command line, OTLPINF_* environment variables, the
+// configuration file given with --config and the flag defaults
+func loadConfig(flags *pflag.FlagSet) (config.Config, error) {
+	v := viper.New()
+	var bindErr error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Name == configFlag || bindErr != nil {
+			return
+		}
+		key := configKey(f.Name)
+		if bindErr = v.BindPFlag(key, f); bindErr == nil {
+			bindErr = v.BindEnv(key, envVar(key))
+		}
+	})
+	if bindErr != nil {
+		return config.Config{}, bindErr
+	}
+
+	path, err := flags.GetString(configFlag)
+	if err != nil {
+		return config.Config{}, err
+	}
+	if path != "" {
+		v.SetConfigFile(path)
+		if err = v.ReadInConfig(); err != nil {
+			return config.Config{}, fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+
+	var cfg config.Config
+	err = v.UnmarshalExact(&cfg, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		stringToMapHookFunc(),
+	)))
+	if err != nil {
+		return config.Config{}, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// stringToMapHookFunc decodes name=value pairs separated by commas, as given
+// to --distribution, into a map
+func stringToMapHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || t.Kind() != reflect.Map {
+			return data, nil
+		}
+		raw := strings.Trim(data.(string), "[]")
+		m := make(map[string]string)
+		if raw == "" {
+			return m, nil
+		}
+		for _, pair := range strings.Split(raw, ",") {
+			k, val, ok := strings.Cut(pair, "=")
+			if !ok {
+				return nil, fmt.Errorf("%q must be formatted as name=value", pair)
+			}
+			m[strings.TrimSpace(k)] = strings.TrimSpace(val)
+		}
+		return m, nil
+	}
+}
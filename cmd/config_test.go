@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+func newRunFlags(t *testing.T, args ...string) *pflag.FlagSet {
+	t.Helper()
+	flags := pflag.NewFlagSet("run", pflag.ContinueOnError)
+	addRunFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return flags
+}
+
+// loadConfig uses the flag defaults when nothing else is configured.
+func TestLoadConfigDefaults(t *testing.T) {
+	cfg, err := loadConfig(newRunFlags(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.ServerHost != "localhost" || cfg.ServerPort != 10222 || cfg.DrainTimeout != 10*time.Second || !cfg.LogTimestamp {
+		t.Errorf("unexpected defaults %+v", cfg)
+	}
+}
+
+// loadConfig gives flags precedence over environment variables, and environment variables over the file.
+func TestLoadConfigPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "otlpinf.yaml")
+	file := "otlpinf_server_host: 0.0.0.0\n" +
+		"otlpinf_server_port: 1000\n" +
+		"otlpinf_drain_timeout: 1m\n" +
+		"otlpinf_max_restarts: 1\n" +
+		"set: [a=1, b=2]\n" +
+		"otlpinf_distributions:\n  slim: /opt/otelcol-slim\n"
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Setenv("OTLPINF_SERVER_PORT", "2000")
+	t.Setenv("OTLPINF_MAX_RESTARTS", "2")
+	t.Setenv("OTLPINF_FEATURE_GATES", "gate")
+	t.Setenv("OTLPINF_DEBUG", "true")
+
+	cfg, err := loadConfig(newRunFlags(t, "--config", path, "--max_restarts", "3"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.ServerHost != "0.0.0.0" || cfg.DrainTimeout != time.Minute {
+		t.Errorf("expected the file values, got %+v", cfg)
+	}
+	if cfg.ServerPort != 2000 || cfg.FeatureGates != "gate" || !cfg.Debug {
+		t.Errorf("expected the environment values, got %+v", cfg)
+	}
+	if cfg.MaxRestarts != 3 {
+		t.Errorf("expected the flag value, got %d", cfg.MaxRestarts)
+	}
+	if !reflect.DeepEqual(cfg.Set, []string{"a=1", "b=2"}) {
+		t.Errorf("unexpected set %v", cfg.Set)
+	}
+	if !reflect.DeepEqual(cfg.Distributions, map[string]string{"slim": "/opt/otelcol-slim"}) {
+		t.Errorf("unexpected distributions %v", cfg.Distributions)
+	}
+}
+
+// loadConfig parses list and map values given as environment variables.
+func TestLoadConfigEnvLists(t *testing.T) {
+	t.Setenv("OTLPINF_SET", "a=1,b=2")
+	t.Setenv("OTLPINF_DISTRIBUTIONS", "slim=/opt/otelcol-slim,tiny=/opt/otelcol-tiny")
+
+	cfg, err := loadConfig(newRunFlags(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.Set, []string{"a=1", "b=2"}) {
+		t.Errorf("unexpected set %v", cfg.Set)
+	}
+	if len(cfg.Distributions) != 2 || cfg.Distributions["tiny"] != "/opt/otelcol-tiny" {
+		t.Errorf("unexpected distributions %v", cfg.Distributions)
+	}
+}
+
+// loadConfig rejects unknown keys and missing files.
+func TestLoadConfigErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "otlpinf.yaml")
+	if err := os.WriteFile(path, []byte("otlpinf_server_prot: 1000\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := loadConfig(newRunFlags(t, "--config", path)); err == nil {
+		t.Errorf("expected an error for the unknown key")
+	}
+	if _, err := loadConfig(newRunFlags(t, "--config", path+".missing")); err == nil {
+		t.Errorf("expected an error for the missing file")
+	}
+}
@@ -7,8 +7,10 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/netboxlabs/opentelemetry-infinity/config"
 	"github.com/netboxlabs/opentelemetry-infinity/otlpinf"
@@ -16,21 +18,12 @@ import (
 
 const routineKey config.ContextKey = "routine"
 
-type runOptions struct {
-	debug         bool
-	selfTelemetry bool
-	serverHost    string
-	serverPort    uint64
-	set           []string
-	featureGates  string
-	logTimestamp  bool
-}
-
-var runOpts runOptions
-
-func run(_ *cobra.Command, _ []string) error {
-	cfg := buildConfig(runOpts)
-	logger := newLogger(runOpts)
+func run(cmd *cobra.Command, _ []string) error {
+	cfg, err := loadConfig(cmd.Flags())
+	if err != nil {
+		return err
+	}
+	logger := newLogger(cfg)
 
 	signalCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
@@ -59,26 +52,14 @@ func run(_ *cobra.Command, _ []string) error {
 	return nil
 }
 
-func buildConfig(opts runOptions) config.Config {
-	return config.Config{
-		Debug:         opts.debug,
-		SelfTelemetry: opts.selfTelemetry,
-		ServerHost:    opts.serverHost,
-		ServerPort:    opts.serverPort,
-		Set:           opts.set,
-		FeatureGates:  opts.featureGates,
-		LogTimestamp:  opts.logTimestamp,
-	}
-}
-
-func newLogger(opts runOptions) *slog.Logger {
+func newLogger(cfg config.Config) *slog.Logger {
 	level := slog.LevelInfo
-	if opts.debug {
+	if cfg.Debug {
 		level = slog.LevelDebug
 	}
 
 	handlerOpts := &slog.HandlerOptions{Level: level}
-	if !opts.logTimestamp {
+	if !cfg.LogTimestamp {
 		handlerOpts.ReplaceAttr = func(_ []string, attr slog.Attr) slog.Attr {
 			if attr.Key == slog.TimeKey {
 				return slog.Attr{}
@@ -125,16 +106,50 @@ func main() {
 		RunE:  run,
 	}
 
-	runCmd.PersistentFlags().BoolVarP(&runOpts.debug, "debug", "d", false, "Enable verbose (debug level) output")
-	runCmd.PersistentFlags().BoolVarP(&runOpts.selfTelemetry, "self_telemetry", "s", false, "Enable self telemetry for collectors. It is disabled by default to avoid port conflict")
-	runCmd.PersistentFlags().StringVarP(&runOpts.serverHost, "server_host", "a", "localhost", "Define REST Host")
-	runCmd.PersistentFlags().Uint64VarP(&runOpts.serverPort, "server_port", "p", 10222, "Define REST Port")
-	runCmd.PersistentFlags().StringSliceVarP(&runOpts.set, "set", "e", nil, "Define opentelemetry set")
-	runCmd.PersistentFlags().StringVarP(&runOpts.featureGates, "feature_gates", "f", "", "Define opentelemetry feature gates")
-	runCmd.PersistentFlags().BoolVar(&runOpts.logTimestamp, "log_timestamp", true, "Include timestamps in logs")
+	addRunFlags(runCmd.PersistentFlags())
 
 	rootCmd.AddCommand(runCmd)
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
 }
+
+func addRunFlags(flags *pflag.FlagSet) {
+	flags.String(configFlag, "", "Path to a YAML configuration file. Flags take precedence over OTLPINF_* environment variables, which take precedence over the file")
+	flags.BoolP("debug", "d", false, "Enable verbose (debug level) output")
+	flags.BoolP("self_telemetry", "s", false, "Enable self telemetry for collectors. It is disabled by default to avoid port conflict")
+	flags.StringP("server_host", "a", "localhost", "Define REST Host")
+	flags.Uint64P("server_port", "p", 10222, "Define REST Port")
+	flags.StringSliceP("set", "e", nil, "Define opentelemetry set")
+	flags.StringP("feature_gates", "f", "", "Define opentelemetry feature gates")
+	flags.Bool("log_timestamp", true, "Include timestamps in logs")
+	flags.String("collector_path", "", "Path to an opentelemetry collector binary to run instead of the embedded otelcol-contrib")
+	flags.StringToString("distribution", nil, "Define an additional collector distribution policies can run on, as name=path")
+	flags.Duration("restart_backoff", time.Second, "Initial delay before restarting a crashed collector")
+	flags.Duration("restart_backoff_max", time.Minute, "Maximum delay between collector restarts")
+	flags.Float64("restart_jitter", 0.2, "Random jitter applied to the restart delay, as a fraction of it")
+	flags.Int("max_restarts", 5, "Maximum collector restarts within the restart window before the policy is marked as crash looping (0 disables)")
+	flags.Duration("restart_window", 5*time.Minute, "Time window used to count collector restarts for crash loop detection")
+	flags.Duration("drain_timeout", 10*time.Second, "Time a stopping collector is given to drain its pipelines after SIGTERM before it is killed (0 kills immediately)")
+	flags.Duration("startup_timeout", 30*time.Second, "Time a collector is given to report readiness. Without --health_check, a collector still running by then is considered ready")
+	flags.Bool("health_check", false, "Inject a health_check extension on a free local port into each policy and use it to detect collector readiness")
+	flags.Int("log_buffer_size", 1000, "Number of collector log records kept per policy")
+	flags.Int("max_revisions", 10, "Number of revisions kept per policy for rollback")
+	flags.String("data_dir", "", "Directory where applied policies are persisted and restored from on start (disabled by default)")
+	flags.String("policies_from", "", "Directory of policy files (*.yaml) that are applied and kept in sync with the directory contents")
+	flags.Bool("ready_with_failed_policies", false, "Report otlpinf as ready on /readyz even when policies failed to restore or are crash looping")
+	flags.String("auth_tokens_file", "", "File of principal:token lines holding the bearer tokens accepted by the REST API")
+	flags.String("auth_basic_file", "", "htpasswd file of user:bcrypt-hash lines holding the basic credentials accepted by the REST API")
+	flags.String("auth_jwks_file", "", "JWKS file holding the keys of the bearer JWTs accepted by the REST API")
+	flags.String("auth_jwt_issuer", "", "Issuer (iss) required in bearer JWTs")
+	flags.String("auth_jwt_audience", "", "Audience (aud) required in bearer JWTs")
+	flags.String("audit_file", "", "JSON lines file recording every operation changing policies, disabled if empty")
+	flags.Int("audit_max_size", 100, "Size in MiB the audit file is rotated at")
+	flags.Int("audit_max_files", 5, "Number of rotated audit files kept")
+	flags.String("authz_file", "", "Role bindings file authorizing authenticated principals on policies")
+	flags.String("tls_cert_file", "", "Certificate file of the REST server, enables TLS together with --tls_key_file")
+	flags.String("tls_key_file", "", "Private key file of the REST server certificate")
+	flags.String("tls_client_ca_file", "", "CA certificates file that REST clients must present a certificate signed by (mutual TLS)")
+	flags.String("tls_min_version", "1.2", "Minimum TLS version accepted by the REST server (1.2 or 1.3)")
+	flags.String("otlp_endpoint", "", "OTLP/HTTP endpoint, such as http://localhost:4318, otlpinf exports its own traces and metrics to")
+}
@@ -10,6 +10,8 @@ type Status struct {
 	StartTime time.Time     `json:"start_time"`
 	UpTime    time.Duration `json:"up_time"`
 	Version   string        `json:"version"`
+
+	Distributions map[string]string `json:"distributions,omitempty"`
 }
 
 // Policy represents the configuration of the opentelemetry collector
@@ -19,15 +21,59 @@ type Policy struct {
 	Exporters  map[string]interface{} `yaml:"exporters"`
 	Extensions map[string]interface{} `yaml:"extensions,omitempty"`
 	Service    map[string]interface{} `yaml:"service"`
+	Otlpinf    PolicyOptions          `yaml:"otlpinf,omitempty"`
+}
+
+// PolicyOptions represents the otlpinf settings of a policy, which are not
+// passed to the opentelemetry collector
+type PolicyOptions struct {
+	DrainTimeout time.Duration     `yaml:"drain_timeout,omitempty"`
+	Distribution string            `yaml:"distribution,omitempty"`
+	Version      string            `yaml:"version,omitempty"`
+	Labels       map[string]string `yaml:"labels,omitempty"`
 }
 
 // Config represents the configuration of the opentelemetry collector
 type Config struct {
-	Debug         bool     `mapstructure:"otlpinf_debug"`
-	SelfTelemetry bool     `mapstructure:"otlpinf_self_telemetry"`
-	ServerHost    string   `mapstructure:"otlpinf_server_host"`
-	ServerPort    uint64   `mapstructure:"otlpinf_server_port"`
-	FeatureGates  string   `mapstructure:"feature_gates"`
-	Set           []string `mapstructure:"set"`
-	LogTimestamp  bool     `mapstructure:"otlpinf_log_timestamp"`
+	Debug         bool              `mapstructure:"otlpinf_debug"`
+	SelfTelemetry bool              `mapstructure:"otlpinf_self_telemetry"`
+	ServerHost    string            `mapstructure:"otlpinf_server_host"`
+	ServerPort    uint64            `mapstructure:"otlpinf_server_port"`
+	FeatureGates  string            `mapstructure:"feature_gates"`
+	Set           []string          `mapstructure:"set"`
+	LogTimestamp  bool              `mapstructure:"otlpinf_log_timestamp"`
+	CollectorPath string            `mapstructure:"otlpinf_collector_path"`
+	Distributions map[string]string `mapstructure:"otlpinf_distributions"`
+
+	RestartBackoff    time.Duration `mapstructure:"otlpinf_restart_backoff"`
+	RestartBackoffMax time.Duration `mapstructure:"otlpinf_restart_backoff_max"`
+	RestartJitter     float64       `mapstructure:"otlpinf_restart_jitter"`
+	MaxRestarts       int           `mapstructure:"otlpinf_max_restarts"`
+	RestartWindow     time.Duration `mapstructure:"otlpinf_restart_window"`
+	DrainTimeout      time.Duration `mapstructure:"otlpinf_drain_timeout"`
+	StartupTimeout    time.Duration `mapstructure:"otlpinf_startup_timeout"`
+	HealthCheck       bool          `mapstructure:"otlpinf_health_check"`
+	LogBufferSize     int           `mapstructure:"otlpinf_log_buffer_size"`
+	MaxRevisions      int           `mapstructure:"otlpinf_max_revisions"`
+	DataDir           string        `mapstructure:"otlpinf_data_dir"`
+	PoliciesFrom      string        `mapstructure:"otlpinf_policies_from"`
+
+	ReadyWithFailedPolicies bool `mapstructure:"otlpinf_ready_with_failed_policies"`
+
+	AuthTokensFile  string `mapstructure:"otlpinf_auth_tokens_file"`
+	AuthBasicFile   string `mapstructure:"otlpinf_auth_basic_file"`
+	AuthJWKSFile    string `mapstructure:"otlpinf_auth_jwks_file"`
+	AuthJWTIssuer   string `mapstructure:"otlpinf_auth_jwt_issuer"`
+	AuthJWTAudience string `mapstructure:"otlpinf_auth_jwt_audience"`
+	AuthzFile       string `mapstructure:"otlpinf_authz_file"`
+	AuditFile       string `mapstructure:"otlpinf_audit_file"`
+	AuditMaxSize    int    `mapstructure:"otlpinf_audit_max_size"`
+	AuditMaxFiles   int    `mapstructure:"otlpinf_audit_max_files"`
+
+	TLSCertFile     string `mapstructure:"otlpinf_tls_cert_file"`
+	TLSKeyFile      string `mapstructure:"otlpinf_tls_key_file"`
+	TLSClientCAFile string `mapstructure:"otlpinf_tls_client_ca_file"`
+	TLSMinVersion   string `mapstructure:"otlpinf_tls_min_version"`
+
+	OTLPEndpoint string `mapstructure:"otlpinf_otlp_endpoint"`
 }
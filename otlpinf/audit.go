@@ -0,0 +1,133 @@
+package otlpinf
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/netboxlabs/opentelemetry-infinity/audit"
+)
+
+const (
+	auditOutcomeKey = "audit_outcome"
+	// defaultAuditLimit and maxAuditLimit bound the number of entries returned
+	// by GET /api/v1/audit
+	defaultAuditLimit = 1000
+	maxAuditLimit     = 10000
+)
+
+type auditLog struct {
+	Verified bool          `json:"verified"`
+	Error    string        `json:"error,omitempty"`
+	Entries  []audit.Entry `json:"entries"`
+}
+
+// audited records the outcome of a request changing policies in the audit
+// log, including requests that were not authorized
+func (o *OltpInf) audited(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if o.audit == nil {
+			c.Next()
+			return
+		}
+		var policies []string
+		if policy := c.Param("policy"); policy != "" {
+			policies = append(policies, policy)
+		} else {
+			for policy := range bodyPolicies(c) {
+				policies = append(policies, policy)
+			}
+			sort.Strings(policies)
+		}
+		dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		outcome := c.GetString(auditOutcomeKey)
+		switch {
+		case outcome != "":
+		case status == http.StatusUnauthorized || status == http.StatusForbidden:
+			outcome = "denied"
+		case status >= http.StatusBadRequest:
+			outcome = "failed"
+		default:
+			outcome = "succeeded"
+		}
+		p, _ := principal(c)
+		o.recordAudit(audit.Entry{
+			Principal: p.Name,
+			Client:    c.ClientIP(),
+			Action:    action,
+			Policies:  policies,
+			DryRun:    dryRun,
+			Status:    status,
+			Outcome:   outcome,
+		})
+	}
+}
+
+// setAuditOutcome sets the outcome of a request to the result of applying its
+// policies, which tells rolled back changes apart from failed ones
+func setAuditOutcome(c *gin.Context, result updateResult) {
+	c.Set(auditOutcomeKey, result.Result)
+}
+
+func (o *OltpInf) recordAudit(e audit.Entry) {
+	if o.audit == nil {
+		return
+	}
+	if err := o.audit.Record(e); err != nil {
+		o.logger.Error("failed to record audit entry", "action", e.Action, "policies", e.Policies, "error", err)
+	}
+}
+
+func (o *OltpInf) getAudit(c *gin.Context) {
+	if o.audit == nil {
+		c.IndentedJSON(http.StatusNotFound, returnValue{"audit log is not enabled"})
+		return
+	}
+	limit := defaultAuditLimit
+	if v := c.Query("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > maxAuditLimit {
+			c.IndentedJSON(http.StatusBadRequest, returnValue{"invalid 'limit' parameter, expected a number from 1 to " + strconv.Itoa(maxAuditLimit)})
+			return
+		}
+	}
+	policy := c.Query("policy")
+	match := func(e audit.Entry) bool {
+		return policy == "" || containsPolicy(e.Policies, policy)
+	}
+	entries, err := o.audit.Tail(limit, match)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, returnValue{err.Error()})
+		return
+	}
+
+	ret := auditLog{Verified: true, Entries: []audit.Entry{}}
+	if err = audit.Verify(entries); err != nil {
+		ret.Verified = false
+		ret.Error = err.Error()
+	}
+	for _, e := range entries {
+		if match(e) {
+			ret.Entries = append(ret.Entries, e)
+		}
+	}
+	if len(ret.Entries) > limit {
+		ret.Entries = ret.Entries[len(ret.Entries)-limit:]
+	}
+	c.IndentedJSON(http.StatusOK, ret)
+}
+
+func containsPolicy(policies []string, policy string) bool {
+	for _, p := range policies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
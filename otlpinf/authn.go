@@ -0,0 +1,50 @@
+package otlpinf
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/netboxlabs/opentelemetry-infinity/auth"
+)
+
+const principalKey = "principal"
+
+// authenticate rejects requests without valid credentials when authentication
+// is enabled and records the principal of the others
+func (o *OltpInf) authenticate(c *gin.Context) {
+	if len(o.auth) == 0 {
+		c.Next()
+		return
+	}
+	p, err := o.auth.Authenticate(c.Request)
+	if err != nil {
+		o.logger.Warn("request authentication failed", "method", c.Request.Method, "path", c.Request.URL.Path,
+			"client", c.ClientIP(), "error", err)
+		for _, challenge := range o.auth.Challenges() {
+			c.Writer.Header().Add("WWW-Authenticate", challenge)
+		}
+		c.IndentedJSON(http.StatusUnauthorized, returnValue{"authentication required: " + err.Error()})
+		c.Abort()
+		return
+	}
+	c.Set(principalKey, p)
+
+	level := o.logger.Debug
+	if c.Request.Method != http.MethodGet {
+		level = o.logger.Info
+	}
+	level("request authenticated", "principal", p.Name, "auth_method", p.Method, "method", c.Request.Method,
+		"path", c.Request.URL.Path, "client", c.ClientIP())
+	c.Next()
+}
+
+// principal returns the authenticated principal of a request
+func principal(c *gin.Context) (auth.Principal, bool) {
+	v, ok := c.Get(principalKey)
+	if !ok {
+		return auth.Principal{}, false
+	}
+	p, ok := v.(auth.Principal)
+	return p, ok
+}
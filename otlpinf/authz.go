@@ -0,0 +1,113 @@
+package otlpinf
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/netboxlabs/opentelemetry-infinity/auth"
+	"github.com/netboxlabs/opentelemetry-infinity/config"
+)
+
+// authorize rejects requests whose principal is not granted a role allowing
+// the action on the policies of the request when authorization is enabled.
+// These are the policy of the route and the policies of a YAML request body,
+// so that principals cannot create policies outside of their scope.
+func (o *OltpInf) authorize(action auth.Action) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if o.authz == nil {
+			c.Next()
+			return
+		}
+		p, _ := principal(c)
+		targets := o.authzTargets(c)
+		if o.authz.Allowed(p.Name, action, targets...) {
+			c.Next()
+			return
+		}
+		o.deny(c, p.Name, action, targets)
+	}
+}
+
+// authorizeUnscoped rejects requests whose principal is not granted a role
+// allowing the action on every policy when authorization is enabled. It
+// guards the routes whose data spans policies, such as the audit log and the
+// metrics, which scoped bindings do not give access to.
+func (o *OltpInf) authorizeUnscoped(action auth.Action) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if o.authz == nil {
+			c.Next()
+			return
+		}
+		p, _ := principal(c)
+		if o.authz.AllowedUnscoped(p.Name, action) {
+			c.Next()
+			return
+		}
+		o.logger.Warn("request not authorized", "principal", p.Name, "action", action.String(), "policies", "*",
+			"method", c.Request.Method, "path", c.Request.URL.Path, "client", c.ClientIP())
+		c.IndentedJSON(http.StatusForbidden, returnValue{"principal '" + p.Name + "' is not allowed to " + action.String() + " every policy"})
+		c.Abort()
+	}
+}
+
+// authorizeResult checks that the principal of a request may also write the
+// policy resulting from a patch or rollback, whose labels may differ from the
+// applied policy the request was authorized against. On refusal the error
+// response has been written and false is returned.
+func (o *OltpInf) authorizeResult(c *gin.Context, policy string, data config.Policy) bool {
+	if o.authz == nil {
+		return true
+	}
+	p, _ := principal(c)
+	target := auth.Target{Name: policy, Labels: data.Otlpinf.Labels}
+	if o.authz.Allowed(p.Name, auth.ActionWrite, target) {
+		return true
+	}
+	o.deny(c, p.Name, auth.ActionWrite, []auth.Target{target})
+	return false
+}
+
+// deny logs and rejects a request that is not authorized
+func (o *OltpInf) deny(c *gin.Context, principal string, action auth.Action, targets []auth.Target) {
+	names := make([]string, 0, len(targets))
+	for _, t := range targets {
+		names = append(names, t.Name)
+	}
+	o.logger.Warn("request not authorized", "principal", principal, "action", action.String(), "policies", names,
+		"method", c.Request.Method, "path", c.Request.URL.Path, "client", c.ClientIP())
+	msg := "principal '" + principal + "' is not allowed to " + action.String() + " policies"
+	if policy := c.Param("policy"); policy != "" {
+		msg = "principal '" + principal + "' is not allowed to " + action.String() + " policy '" + policy + "'"
+	}
+	c.IndentedJSON(http.StatusForbidden, returnValue{msg})
+	c.Abort()
+}
+
+// authzTargets returns the policies a request acts on, with the labels of
+// the applied policy and of the policy in the request body
+func (o *OltpInf) authzTargets(c *gin.Context) []auth.Target {
+	var targets []auth.Target
+	if policy := c.Param("policy"); policy != "" {
+		info, ok := o.policies.get(policy)
+		t := auth.Target{Name: policy}
+		if ok {
+			t.Labels = info.Policy.Otlpinf.Labels
+		}
+		targets = append(targets, t)
+	}
+	for policy, data := range bodyPolicies(c) {
+		targets = append(targets, auth.Target{Name: policy, Labels: data.Otlpinf.Labels})
+	}
+	return targets
+}
+
+// visible reports whether the principal of a request may read a policy
+func (o *OltpInf) visible(c *gin.Context, policy string) bool {
+	if o.authz == nil {
+		return true
+	}
+	p, _ := principal(c)
+	info, _ := o.policies.get(policy)
+	return o.authz.Allowed(p.Name, auth.ActionRead, auth.Target{Name: policy, Labels: info.Policy.Otlpinf.Labels})
+}
@@ -2,16 +2,24 @@ package otlpinf
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/netboxlabs/opentelemetry-infinity/audit"
+	"github.com/netboxlabs/opentelemetry-infinity/auth"
 	"github.com/netboxlabs/opentelemetry-infinity/config"
+	"github.com/netboxlabs/opentelemetry-infinity/runner"
+	"github.com/netboxlabs/opentelemetry-infinity/store"
 )
 
 func newTestOtlp() *OltpInf {
@@ -24,7 +32,7 @@ func newTestOtlp() *OltpInf {
 // getCapabilities returns 400 when the stored capabilities are not valid YAML.
 func TestGetCapabilitiesError(t *testing.T) {
 	o := newTestOtlp()
-	o.capabilities = []byte("{") // invalid YAML -> yson.YAMLToJSON fails
+	o.distributions.Default().Capabilities = []byte("{") // invalid YAML -> yson.YAMLToJSON fails
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/api/v1/capabilities", nil)
@@ -35,10 +43,73 @@ func TestGetCapabilitiesError(t *testing.T) {
 	}
 }
 
+// getCapabilities returns 404 for an unknown distribution.
+func TestGetCapabilitiesUnknownDistribution(t *testing.T) {
+	o := newTestOtlp()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/capabilities?distribution=missing", nil)
+	o.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+// createPolicy returns 400 before starting any collector when the distribution cannot be resolved.
+func TestCreatePolicyUnknownDistribution(t *testing.T) {
+	o := newTestOtlp()
+	o.distributions.Default().Version = "0.154.0"
+
+	bodies := []string{
+		"p1:\n  otlpinf:\n    distribution: missing\n  receivers:\n    otlp:\n  exporters:\n    debug:\n  service: {}\n",
+		"p1:\n  otlpinf:\n    version: 0.1.0\n  receivers:\n    otlp:\n  exporters:\n    debug:\n  service: {}\n",
+	}
+	for _, body := range bodies {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", PoliciesAPI, strings.NewReader(body))
+		req.Header.Set("Content-Type", HTTPYamlContent)
+		o.router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", w.Code)
+		}
+		if len(o.policies.names()) != 0 {
+			t.Errorf("expected no policy to be created, got %v", o.policies.names())
+		}
+	}
+}
+
+// createPolicy returns the collector validation output as a structured 400 without starting a runner.
+func TestCreatePolicyValidationError(t *testing.T) {
+	o := newTestOtlp()
+	o.policiesDir = t.TempDir()
+
+	body := "p1:\n  receivers:\n    invalid:\n  exporters:\n    debug:\n  service:\n    pipelines:\n      metrics:\n        receivers: [invalid]\n        exporters: [debug]\n"
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", PoliciesAPI, strings.NewReader(body))
+	req.Header.Set("Content-Type", HTTPYamlContent)
+	o.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+	var resp validationFailure
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unexpected body %s: %v", w.Body.String(), err)
+	}
+	if resp.Policy != "p1" || len(resp.Errors) == 0 {
+		t.Errorf("expected validation errors for p1, got %+v", resp)
+	}
+	if len(o.policies.names()) != 0 {
+		t.Errorf("expected no policy to be created, got %v", o.policies.names())
+	}
+}
+
 // createPolicy returns 409 when the policy already exists (no collector started).
 func TestCreatePolicyConflict(t *testing.T) {
 	o := newTestOtlp()
-	o.policies["existing"] = RunnerInfo{}
+	o.policies.set("existing", RunnerInfo{})
 
 	body := "existing:\n  receivers:\n    otlp:\n  exporters:\n    debug:\n  service: {}\n"
 	w := httptest.NewRecorder()
@@ -67,6 +138,96 @@ func TestCreatePolicyConfigureError(t *testing.T) {
 	}
 }
 
+// resetPolicy returns 404 for unknown policies and 409 when the runner is not crash looping.
+func TestResetPolicy(t *testing.T) {
+	o := newTestOtlp()
+	o.policies.set("idle", RunnerInfo{Instance: runner.NewRunner(o.logger, "idle", o.policiesDir, o.conf)})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", PoliciesAPI+"/missing/reset", nil)
+	o.router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", PoliciesAPI+"/idle/reset", nil)
+	o.router.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409, got %d", w.Code)
+	}
+}
+
+// getPolicyLogs returns 404 for unknown policies, 400 on invalid filters and the buffered records otherwise.
+func TestGetPolicyLogs(t *testing.T) {
+	o := newTestOtlp()
+	o.policies.set("p1", RunnerInfo{Instance: runner.NewRunner(o.logger, "p1", o.policiesDir, o.conf)})
+
+	cases := []struct {
+		url  string
+		code int
+	}{
+		{PoliciesAPI + "/missing/logs", http.StatusNotFound},
+		{PoliciesAPI + "/p1/logs", http.StatusOK},
+		{PoliciesAPI + "/p1/logs?level=warn&limit=10&since=2024-01-01T00:00:00Z", http.StatusOK},
+		{PoliciesAPI + "/p1/logs?level=loud", http.StatusBadRequest},
+		{PoliciesAPI + "/p1/logs?limit=0", http.StatusBadRequest},
+		{PoliciesAPI + "/p1/logs?since=yesterday", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", tc.url, nil)
+		o.router.ServeHTTP(w, req)
+		if w.Code != tc.code {
+			t.Errorf("GET %s: expected %d, got %d", tc.url, tc.code, w.Code)
+		}
+	}
+}
+
+// streamPolicyLogs returns 404 for unknown policies and holds an event stream open until the client leaves.
+func TestStreamPolicyLogs(t *testing.T) {
+	o := newTestOtlp()
+	o.policies.set("p1", RunnerInfo{Instance: runner.NewRunner(o.logger, "p1", o.policiesDir, o.conf)})
+	srv := httptest.NewServer(o.router)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + PoliciesAPI + "/missing/logs/stream")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(srv.URL + PoliciesAPI + "/p1/logs/stream?level=loud")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", resp.StatusCode)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+PoliciesAPI+"/p1/logs/stream?level=warn", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Errorf("expected an event stream, got %q", ct)
+	}
+	cancel()
+	if _, err = io.ReadAll(resp.Body); err == nil {
+		t.Errorf("expected the stream to be interrupted by the client")
+	}
+}
+
 // Stop with no http server removes the policies dir and clears state.
 func TestStopRemovesPoliciesDir(t *testing.T) {
 	o := newTestOtlp()
@@ -97,6 +258,22 @@ func TestStopShutsDownServer(t *testing.T) {
 	}
 }
 
+// Start fails when the configured collector binary cannot be executed.
+func TestStartInvalidCollectorPath(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	o := NewOtlp(logger, &config.Config{ServerHost: TestHost, CollectorPath: "/nonexistent/otelcol"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := o.Start(ctx, cancel)
+	if err, ok := <-ch; !ok || err == nil {
+		t.Fatalf("expected an error on the channel, ok=%v err=%v", ok, err)
+	}
+	if o.policiesDir != "" {
+		t.Errorf("expected policiesDir cleared, got %q", o.policiesDir)
+	}
+}
+
 // startFailure delivers the error and cleans up an existing policies dir.
 func TestStartFailureCleansPoliciesDir(t *testing.T) {
 	o := newTestOtlp()
@@ -118,3 +295,692 @@ func TestStartFailureCleansPoliciesDir(t *testing.T) {
 		t.Errorf("expected dir %q removed", dir)
 	}
 }
+
+const updateCollectorScript = `#!/bin/sh
+case "$1" in
+components) printf 'buildinfo:\n  command: otelcol-custom\n  version: 1.2.3\n'; exit 0 ;;
+validate) exit 0 ;;
+esac
+if [ -e "$0.fail" ] || grep -q broken "$2"; then
+	printf 'ts\terror\tsrc\tfailed to start pipelines\n' >&2
+	exit 1
+fi
+if grep -q slow "$2"; then
+	sleep 1
+fi
+printf 'ts\tinfo\tsrc\tEverything is ready. Begin running and processing data.\n' >&2
+exec sleep 60
+`
+
+func newUpdateTestOtlp(t *testing.T) *OltpInf {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "otelcol-custom")
+	if err := os.WriteFile(path, []byte(updateCollectorScript), 0o755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	o := NewOtlp(logger, &config.Config{ServerHost: TestHost, CollectorPath: path})
+	o.setupRouter()
+	o.ctx = context.Background()
+	o.policiesDir = t.TempDir()
+	t.Cleanup(func() {
+		for _, info := range o.policies.all() {
+			info.Instance.Stop(o.ctx)
+		}
+	})
+	return o
+}
+
+// A policy being created or updated is read with the starting status of its new runner.
+func TestPolicyStarting(t *testing.T) {
+	o := newUpdateTestOtlp(t)
+	policy := "receivers:\n    otlp:\n  exporters:\n    debug:\n  service:\n    pipelines:\n      metrics:\n        receivers: [otlp]\n        exporters: [debug]\n"
+	slow := strings.Replace(policy, "debug", "debug/slow", -1)
+	waitStarting := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", PoliciesAPI+"/p1", nil)
+			o.router.ServeHTTP(w, req)
+			if w.Code == http.StatusOK && strings.Contains(w.Body.String(), "status: starting") && strings.Contains(w.Body.String(), want) {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Errorf("expected p1 to be read as starting with %s", want)
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", PoliciesAPI, strings.NewReader("p1:\n  "+slow))
+		req.Header.Set("Content-Type", HTTPYamlContent)
+		o.router.ServeHTTP(w, req)
+		done <- w
+	}()
+	waitStarting("debug/slow")
+	if w := <-done; w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	go func() { done <- putPolicy(o, "p1", "p1:\n  "+strings.Replace(slow, "debug/slow", "debug/slow2", -1)) }()
+	waitStarting("debug/slow2")
+	if w := <-done; w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// appliedPolicy returns the stored policy of an applied policy
+func appliedPolicy(o *OltpInf, policy string) config.Policy {
+	info, _ := o.policies.get(policy)
+	return info.Policy
+}
+
+func putPolicy(o *OltpInf, policy string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", PoliciesAPI+"/"+policy, strings.NewReader(body))
+	req.Header.Set("Content-Type", HTTPYamlContent)
+	o.router.ServeHTTP(w, req)
+	return w
+}
+
+// updatePolicy returns 404 for unknown policies and 400 when the body does not hold exactly the policy.
+func TestUpdatePolicyInvalidRequest(t *testing.T) {
+	o := newTestOtlp()
+	o.policies.set("p1", RunnerInfo{})
+
+	body := "p2:\n  receivers:\n    otlp:\n  exporters:\n    debug:\n  service: {}\n"
+	if w := putPolicy(o, "missing", body); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+	if w := putPolicy(o, "p1", body); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+// updatePolicy replaces a running policy and rolls back to the previous one when the new collector fails to start.
+func TestUpdatePolicyRollback(t *testing.T) {
+	o := newUpdateTestOtlp(t)
+	policy := "receivers:\n    otlp:\n  exporters:\n    debug:\n  service:\n    pipelines:\n      metrics:\n        receivers: [otlp]\n        exporters: [debug]\n"
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", PoliciesAPI, strings.NewReader("p1:\n  "+policy))
+	req.Header.Set("Content-Type", HTTPYamlContent)
+	o.router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = putPolicy(o, "p1", "p1:\n  "+strings.Replace(policy, "debug", "debug/updated", -1))
+	var resp updateResult
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unexpected body %s: %v", w.Body.String(), err)
+	}
+	if w.Code != http.StatusOK || resp.Result != "applied" {
+		t.Fatalf("expected the update to be applied, got %d: %+v", w.Code, resp)
+	}
+	if _, ok := appliedPolicy(o, "p1").Exporters["debug/updated"]; !ok {
+		t.Errorf("expected the stored policy to be updated, got %+v", appliedPolicy(o, "p1"))
+	}
+
+	w = putPolicy(o, "p1", "p1:\n  "+strings.Replace(policy, "debug", "broken", -1))
+	resp = updateResult{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unexpected body %s: %v", w.Body.String(), err)
+	}
+	if w.Code != http.StatusBadRequest || resp.Result != "rolled_back" || !strings.Contains(resp.Reason, "failed to start pipelines") {
+		t.Fatalf("expected the update to be rolled back, got %d: %+v", w.Code, resp)
+	}
+	info, _ := o.policies.get("p1")
+	if _, ok := info.Policy.Exporters["debug/updated"]; !ok {
+		t.Errorf("expected the previous policy to be restored, got %+v", info.Policy)
+	}
+	if s := info.Instance.GetStatus(); s.StatusText != "running" {
+		t.Errorf("expected the previous policy to be running, got %q", s.StatusText)
+	}
+	if entries, err := os.ReadDir(o.policiesDir); err != nil || len(entries) != 1 {
+		t.Errorf("expected only the policy file of the running collector to be kept, got %v (%v)", entries, err)
+	}
+}
+
+// A policy whose previous version fails to start again after a failed update is kept, and persisted, as failed until it is reset.
+func TestUpdatePolicyRollbackFailure(t *testing.T) {
+	o := newUpdateTestOtlp(t)
+	var err error
+	o.conf.DataDir = t.TempDir()
+	if o.store, err = store.New(o.conf.DataDir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	policy := "receivers:\n    otlp:\n  exporters:\n    debug:\n  service:\n    pipelines:\n      metrics:\n        receivers: [otlp]\n        exporters: [debug]\n"
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", PoliciesAPI, strings.NewReader("p1:\n  "+policy))
+	req.Header.Set("Content-Type", HTTPYamlContent)
+	o.router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	// Collectors fail to start from now on, including the one of the previous policy
+	fail := o.conf.CollectorPath + ".fail"
+	if err = os.WriteFile(fail, nil, 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	w = putPolicy(o, "p1", "p1:\n  "+strings.Replace(policy, "debug", "debug/updated", -1))
+	var resp updateResult
+	if err = json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unexpected body %s: %v", w.Body.String(), err)
+	}
+	if w.Code != http.StatusInternalServerError || resp.Result != "failed" {
+		t.Fatalf("expected the rollback to fail, got %d: %+v", w.Code, resp)
+	}
+	info, ok := o.policies.get("p1")
+	if !ok {
+		t.Fatalf("expected p1 to be kept")
+	}
+	if _, ok = info.Policy.Exporters["debug"]; !ok {
+		t.Errorf("expected the previous policy to be kept, got %+v", info.Policy)
+	}
+	if s := info.Instance.GetStatus(); s.StatusText != "failed" {
+		t.Errorf("expected p1 to be failed, got %q", s.StatusText)
+	}
+	records, err := o.store.Load()
+	if _, ok = records["p1"].Policy.Exporters["debug"]; err != nil || !ok {
+		t.Errorf("expected the previous policy to stay persisted, got %+v (%v)", records, err)
+	}
+	if n := len(o.policies.revisionHistory("p1")); n != 2 {
+		t.Errorf("expected the revisions to be kept, got %d", n)
+	}
+
+	if err = os.Remove(fail); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", PoliciesAPI+"/p1/reset", nil)
+	o.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if info, _ = o.policies.get("p1"); info.Instance.GetStatus().StatusText != "running" {
+		t.Errorf("expected p1 to be running once reset, got %q", info.Instance.GetStatus().StatusText)
+	}
+}
+
+// createPolicy stops the started policies and removes every policy file when one of the policies fails to start.
+func TestCreatePolicyStartFailure(t *testing.T) {
+	o := newUpdateTestOtlp(t)
+	body := "p1:\n  exporters:\n    debug:\np2:\n  exporters:\n    broken:\np3:\n  exporters:\n    debug:\n"
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", PoliciesAPI, strings.NewReader(body))
+	req.Header.Set("Content-Type", HTTPYamlContent)
+	o.router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+	if names := o.policies.names(); len(names) != 0 {
+		t.Errorf("expected no policy to be created, got %v", names)
+	}
+	if entries, err := os.ReadDir(o.policiesDir); err != nil || len(entries) != 0 {
+		t.Errorf("expected policy files to be removed, got %v (%v)", entries, err)
+	}
+}
+
+// The events of a policy list the status transitions of all of its runners
+func TestPolicyEvents(t *testing.T) {
+	o := newUpdateTestOtlp(t)
+	policy := "exporters:\n    debug:\n"
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", PoliciesAPI+"/p1/events", nil)
+	o.router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown policy, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", PoliciesAPI, strings.NewReader("p1:\n  "+policy))
+	req.Header.Set("Content-Type", HTTPYamlContent)
+	o.router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if w = putPolicy(o, "p1", "p1:\n  "+strings.Replace(policy, "debug", "broken", -1)); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected the update to be rolled back, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", PoliciesAPI+"/p1/events", nil)
+	o.router.ServeHTTP(w, req)
+	var events []runner.Transition
+	if err := json.Unmarshal(w.Body.Bytes(), &events); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d %s: %v", w.Code, w.Body.String(), err)
+	}
+	var got []string
+	for _, e := range events {
+		got = append(got, e.From+">"+e.To)
+		if e.Time.IsZero() {
+			t.Errorf("expected the transition to %s to have a time", e.To)
+		}
+	}
+	want := []string{
+		"pending>starting", "starting>running", "running>stopping", "stopping>stopped",
+		"pending>starting", "starting>failed",
+		"pending>starting", "starting>running",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+	if reason := events[5].Reason; !strings.Contains(reason, "failed to start pipelines") {
+		t.Errorf("expected the failure reason to be recorded, got %q", reason)
+	}
+}
+
+func patchPolicyRequest(o *OltpInf, policy string, contentType string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PATCH", PoliciesAPI+"/"+policy, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	o.router.ServeHTTP(w, req)
+	return w
+}
+
+// patchPolicy returns 404 for unknown policies, 415 for unsupported patch formats and 400 for patches that do not apply.
+func TestPatchPolicyInvalidRequest(t *testing.T) {
+	o := newTestOtlp()
+	o.policies.set("p1", RunnerInfo{Policy: config.Policy{Exporters: map[string]interface{}{"debug": nil}}})
+
+	if w := patchPolicyRequest(o, "missing", mergePatchContent, `{}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+	if w := patchPolicyRequest(o, "p1", "text/plain", `{}`); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected 415, got %d", w.Code)
+	}
+	if w := patchPolicyRequest(o, "p1", jsonPatchContent, `[{"op": "remove", "path": "/exporters/missing"}]`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+// patchPolicy applies the patch to the stored policy and restarts it with the merged result.
+func TestPatchPolicy(t *testing.T) {
+	o := newUpdateTestOtlp(t)
+	policy := "receivers:\n    otlp:\n  exporters:\n    debug:\n  service:\n    pipelines:\n      metrics:\n        receivers: [otlp]\n        exporters: [debug]\n"
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", PoliciesAPI, strings.NewReader("p1:\n  "+policy))
+	req.Header.Set("Content-Type", HTTPYamlContent)
+	o.router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	patch := `[{"op": "add", "path": "/exporters/debug~1updated", "value": null},
+		{"op": "add", "path": "/service/pipelines/metrics/exporters/-", "value": "debug/updated"}]`
+	w = patchPolicyRequest(o, "p1", jsonPatchContent, patch)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "debug/updated") {
+		t.Errorf("expected the merged policy in the response, got %s", w.Body.String())
+	}
+	if _, ok := appliedPolicy(o, "p1").Exporters["debug/updated"]; !ok {
+		t.Errorf("expected the stored policy to be patched, got %+v", appliedPolicy(o, "p1"))
+	}
+
+	w = patchPolicyRequest(o, "p1", mergePatchContent, `{"exporters": {"broken": {}}}`)
+	var resp updateResult
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unexpected body %s: %v", w.Body.String(), err)
+	}
+	if w.Code != http.StatusBadRequest || resp.Result != "rolled_back" {
+		t.Fatalf("expected the patch to be rolled back, got %d: %+v", w.Code, resp)
+	}
+	if _, ok := appliedPolicy(o, "p1").Exporters["broken"]; ok {
+		t.Errorf("expected the previous policy to be restored, got %+v", appliedPolicy(o, "p1"))
+	}
+}
+
+// Policy changes are recorded as revisions that the policy can be rolled back to.
+func TestPolicyRevisionsRollback(t *testing.T) {
+	o := newUpdateTestOtlp(t)
+	policy := "receivers:\n    otlp:\n  exporters:\n    debug:\n  service:\n    pipelines:\n      metrics:\n        receivers: [otlp]\n        exporters: [debug]\n"
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", PoliciesAPI, strings.NewReader("p1:\n  "+policy))
+	req.Header.Set("Content-Type", HTTPYamlContent)
+	o.router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if w = putPolicy(o, "p1", "p1:\n  "+strings.Replace(policy, "debug", "debug/updated", -1)); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w = putPolicy(o, "p1", "p1:\n  "+strings.Replace(policy, "debug", "broken", -1)); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", PoliciesAPI+"/p1/revisions", nil)
+	o.router.ServeHTTP(w, req)
+	var revisions []Revision
+	if err := json.Unmarshal(w.Body.Bytes(), &revisions); err != nil {
+		t.Fatalf("unexpected body %s: %v", w.Body.String(), err)
+	}
+	results := make([]string, 0, len(revisions))
+	for _, rev := range revisions {
+		results = append(results, rev.Change+":"+rev.Result)
+	}
+	if strings.Join(results, ",") != "created:applied,updated:applied,updated:rolled_back" {
+		t.Fatalf("unexpected revisions %v", results)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", PoliciesAPI+"/p1/revisions/1", nil)
+	o.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "debug: null") {
+		t.Errorf("expected revision 1 to hold the created policy, got %d: %s", w.Code, w.Body.String())
+	}
+
+	cases := []struct {
+		url  string
+		code int
+	}{
+		{PoliciesAPI + "/missing/rollback?revision=1", http.StatusNotFound},
+		{PoliciesAPI + "/p1/rollback", http.StatusBadRequest},
+		{PoliciesAPI + "/p1/rollback?revision=9", http.StatusNotFound},
+		{PoliciesAPI + "/p1/rollback?revision=3", http.StatusConflict},
+		{PoliciesAPI + "/p1/rollback?revision=1", http.StatusOK},
+	}
+	for _, tc := range cases {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("POST", tc.url, nil)
+		o.router.ServeHTTP(w, req)
+		if w.Code != tc.code {
+			t.Errorf("POST %s: expected %d, got %d: %s", tc.url, tc.code, w.Code, w.Body.String())
+		}
+	}
+	if _, ok := appliedPolicy(o, "p1").Exporters["debug"]; !ok {
+		t.Errorf("expected the policy of revision 1 to be applied, got %+v", appliedPolicy(o, "p1"))
+	}
+	if rev, ok := o.policies.revision("p1", 4); !ok || rev.Change != "rollback" || rev.From != 1 {
+		t.Errorf("expected the rollback to be recorded as revision 4, got %+v", rev)
+	}
+}
+
+// Policies applied with a data dir are restored and started by the next otlpinf instance.
+func TestRestorePolicies(t *testing.T) {
+	dataDir := t.TempDir()
+	o := newUpdateTestOtlp(t)
+	o.conf.DataDir = dataDir
+	var err error
+	if o.store, err = store.New(dataDir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	policy := "receivers:\n    otlp:\n  exporters:\n    debug:\n  service:\n    pipelines:\n      metrics:\n        receivers: [otlp]\n        exporters: [debug]\n"
+
+	for _, name := range []string{"p1", "p2"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", PoliciesAPI, strings.NewReader(name+":\n  "+policy))
+		req.Header.Set("Content-Type", HTTPYamlContent)
+		o.router.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", PoliciesAPI+"/p2", nil)
+	o.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	o.Stop(context.Background())
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	restored := NewOtlp(logger, &config.Config{ServerHost: TestHost, CollectorPath: o.conf.CollectorPath, DataDir: dataDir})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := restored.Start(ctx, cancel)
+	defer restored.Stop(ctx)
+	select {
+	case err := <-errCh:
+		t.Fatalf("unexpected start error: %v", err)
+	default:
+	}
+
+	if len(restored.policies.names()) != 1 {
+		t.Fatalf("expected only p1 to be restored, got %v", restored.policies.names())
+	}
+	info, ok := restored.policies.get("p1")
+	if !ok || info.Instance.GetStatus().StatusText != "running" {
+		t.Fatalf("expected p1 to be restored and running, got %+v", info)
+	}
+	if rev, ok := restored.policies.revision("p1", 1); !ok || rev.Change != "restored" {
+		t.Errorf("expected the restore to be recorded as revision 1, got %+v", rev)
+	}
+}
+
+// With authentication enabled, API requests without valid credentials are rejected with a challenge.
+func TestAuthenticate(t *testing.T) {
+	o := newTestOtlp()
+	tokens := filepath.Join(t.TempDir(), "tokens")
+	if err := os.WriteFile(tokens, []byte("ci:s3cr3t\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var err error
+	if o.auth, err = auth.New(&config.Config{AuthTokensFile: tokens}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, header := range []string{"", "Bearer wrong"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/status", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		o.router.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%q: expected 401, got %d", header, w.Code)
+		}
+		if w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%q: expected a WWW-Authenticate challenge", header)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/status", nil)
+	req.Header.Set("Authorization", "Bearer s3cr3t")
+	o.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+}
+
+// Requests are authorized on the role bindings of their principal and the policies they act on.
+func TestAuthorize(t *testing.T) {
+	o := newTestOtlp()
+	dir := t.TempDir()
+	tokens := filepath.Join(dir, "tokens")
+	if err := os.WriteFile(tokens, []byte("viewer:v\nops:o\nalice:a\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	bindings := filepath.Join(dir, "bindings.yaml")
+	content := "bindings:\n" +
+		"  - principals: [viewer, ops, alice]\n    role: viewer\n    policies:\n      prefixes: [team-a-]\n" +
+		"  - principals: [ops]\n    role: operator\n" +
+		"  - principals: [alice]\n    role: admin\n    policies:\n      labels:\n        team: a\n"
+	if err := os.WriteFile(bindings, []byte(content), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var err error
+	if o.auth, err = auth.New(&config.Config{AuthTokensFile: tokens}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if o.authz, err = auth.NewAuthorizer(bindings); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	o.policies.set("team-a-metrics", RunnerInfo{Policy: config.Policy{Otlpinf: config.PolicyOptions{Labels: map[string]string{"team": "a"}}}})
+	o.policies.set("team-b-metrics", RunnerInfo{})
+
+	request := func(token string, method string, path string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", HTTPYamlContent)
+		o.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("v", "GET", PoliciesAPI, "")
+	var policies []string
+	if err := json.Unmarshal(w.Body.Bytes(), &policies); err != nil {
+		t.Fatalf("unexpected body %s: %v", w.Body.String(), err)
+	}
+	if w.Code != http.StatusOK || len(policies) != 1 || policies[0] != "team-a-metrics" {
+		t.Errorf("expected only the policies in scope to be listed, got %d: %v", w.Code, policies)
+	}
+
+	denied := []struct {
+		token  string
+		method string
+		path   string
+		body   string
+	}{
+		{"v", "GET", PoliciesAPI + "/team-b-metrics", ""},
+		{"v", "POST", PoliciesAPI + "/team-a-metrics/reset", ""},
+		{"o", "DELETE", PoliciesAPI + "/team-a-metrics", ""},
+		{"a", "DELETE", PoliciesAPI + "/team-b-metrics", ""},
+		{"a", "POST", PoliciesAPI, "new:\n  otlpinf:\n    labels:\n      team: b\n"},
+		{"a", "PUT", PoliciesAPI + "/team-a-metrics", "team-a-metrics:\n  otlpinf:\n    labels:\n      team: b\n"},
+		{"a", "GET", "/api/v1/audit", ""},
+		{"v", "GET", "/metrics", ""},
+	}
+	for _, tc := range denied {
+		if w := request(tc.token, tc.method, tc.path, tc.body); w.Code != http.StatusForbidden {
+			t.Errorf("%s %s %s: expected 403, got %d", tc.token, tc.method, tc.path, w.Code)
+		}
+	}
+
+	// Authorized requests reach the handlers, which reject these invalid policies
+	if w := request("a", "POST", PoliciesAPI, "new:\n  otlpinf:\n    labels:\n      team: a\n"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := request("o", "POST", PoliciesAPI+"/missing/reset", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d: %s", w.Code, w.Body.String())
+	}
+	// The metrics span policies and are only served to unscoped bindings
+	if w := request("o", "GET", "/metrics", ""); w.Code == http.StatusForbidden {
+		t.Errorf("expected the metrics to be authorized, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// Patches and rollbacks are authorized against the labels of the resulting policy too.
+func TestAuthorizePatchRollback(t *testing.T) {
+	o := newTestOtlp()
+	dir := t.TempDir()
+	tokens := filepath.Join(dir, "tokens")
+	if err := os.WriteFile(tokens, []byte("alice:a\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	bindings := filepath.Join(dir, "bindings.yaml")
+	if err := os.WriteFile(bindings, []byte("bindings:\n  - principals: [alice]\n    role: admin\n    policies:\n      labels:\n        team: a\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var err error
+	if o.auth, err = auth.New(&config.Config{AuthTokensFile: tokens}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if o.authz, err = auth.NewAuthorizer(bindings); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	teamA := config.Policy{Otlpinf: config.PolicyOptions{Labels: map[string]string{"team": "a"}}}
+	o.policies.set("p1", RunnerInfo{Policy: teamA})
+	o.policies.addRevision("p1", Revision{Number: 1, Change: "created", Result: "applied", Policy: config.Policy{Otlpinf: config.PolicyOptions{Labels: map[string]string{"team": "b"}}}}, 10)
+	o.policies.addRevision("p1", Revision{Number: 2, Change: "updated", Result: "applied", Policy: teamA}, 10)
+
+	request := func(method string, path string, contentType string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer a")
+		req.Header.Set("Content-Type", contentType)
+		o.router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := request("PATCH", PoliciesAPI+"/p1", mergePatchContent, `{"otlpinf":{"labels":{"team":"b"}}}`); w.Code != http.StatusForbidden {
+		t.Errorf("expected a patch moving p1 out of scope to be denied, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := request("POST", PoliciesAPI+"/p1/rollback?revision=1", HTTPYamlContent, ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected a rollback moving p1 out of scope to be denied, got %d: %s", w.Code, w.Body.String())
+	}
+	if labels := appliedPolicy(o, "p1").Otlpinf.Labels; labels["team"] != "a" {
+		t.Errorf("expected p1 to keep its labels, got %v", labels)
+	}
+
+	// Authorized requests reach the pre-flight checks, which reject these invalid policies
+	if w := request("PATCH", PoliciesAPI+"/p1", mergePatchContent, `{"otlpinf":{"labels":{"env":"prod"}}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := request("POST", PoliciesAPI+"/p1/rollback?revision=2", HTTPYamlContent, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// Operations changing policies are recorded in the audit log with their outcome.
+func TestAuditLog(t *testing.T) {
+	o := newUpdateTestOtlp(t)
+	var err error
+	if o.audit, err = audit.New(filepath.Join(t.TempDir(), "audit.log"), 0, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = o.audit.Close() })
+	policy := "receivers:\n    otlp:\n  exporters:\n    debug:\n  service:\n    pipelines:\n      metrics:\n        receivers: [otlp]\n        exporters: [debug]\n"
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", PoliciesAPI, strings.NewReader("p1:\n  "+policy))
+	req.Header.Set("Content-Type", HTTPYamlContent)
+	req.RemoteAddr = "192.0.2.10:41000"
+	o.router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	broken := strings.Replace(policy, "debug:\n", "debug:\n    broken:\n", 1)
+	if w = putPolicy(o, "p1", "p1:\n  "+broken); w.Code == http.StatusOK {
+		t.Fatalf("expected the update to fail, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", PoliciesAPI+"/missing", nil)
+	o.router.ServeHTTP(w, req)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/audit?policy=p1", nil)
+	o.router.ServeHTTP(w, req)
+	var resp auditLog
+	if err = json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unexpected body %s: %v", w.Body.String(), err)
+	}
+	if w.Code != http.StatusOK || !resp.Verified || len(resp.Entries) != 2 {
+		t.Fatalf("expected two verified entries for p1, got %d: %+v", w.Code, resp)
+	}
+	if e := resp.Entries[0]; e.Action != "create" || e.Outcome != "applied" || e.Client != "192.0.2.10" {
+		t.Errorf("unexpected create entry %+v", e)
+	}
+	if e := resp.Entries[1]; e.Action != "update" || e.Outcome != "rolled_back" {
+		t.Errorf("unexpected update entry %+v", e)
+	}
+
+	entries, err := o.audit.Entries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e := entries[len(entries)-1]; e.Action != "delete" || e.Outcome != "failed" || e.Status != http.StatusNotFound {
+		t.Errorf("unexpected delete entry %+v", e)
+	}
+
+	for limit, code := range map[string]int{"1": http.StatusOK, "0": http.StatusBadRequest, "10001": http.StatusBadRequest} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/api/v1/audit?limit="+limit, nil)
+		o.router.ServeHTTP(w, req)
+		if w.Code != code {
+			t.Errorf("expected %d with limit %s, got %d: %s", code, limit, w.Code, w.Body.String())
+		}
+	}
+}
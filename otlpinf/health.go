@@ -0,0 +1,119 @@
+package otlpinf
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	checkOK     = "ok"
+	checkFailed = "failed"
+)
+
+type probeCheck struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+type probeResult struct {
+	Status string                `json:"status"`
+	Checks map[string]probeCheck `json:"checks"`
+}
+
+// probe writes the result of the checks, with 503 if any of them failed
+func probe(c *gin.Context, checks map[string]probeCheck) {
+	ret := probeResult{Status: checkOK, Checks: checks}
+	code := http.StatusOK
+	for _, check := range checks {
+		if check.Status != checkOK {
+			ret.Status = checkFailed
+			code = http.StatusServiceUnavailable
+		}
+	}
+	c.IndentedJSON(code, ret)
+}
+
+// getHealth reports whether otlpinf is alive, i.e. its server answers and it
+// is not shutting down
+func (o *OltpInf) getHealth(c *gin.Context) {
+	server := probeCheck{Status: checkOK}
+	if o.ctx != nil && o.ctx.Err() != nil {
+		server = probeCheck{Status: checkFailed, Message: "otlpinf is shutting down"}
+	}
+	probe(c, map[string]probeCheck{"server": server})
+}
+
+// getReadiness reports whether otlpinf is ready to manage policies: the
+// capabilities of its distributions are loaded, the persisted policies are
+// restored and no policy is failed or crash looping
+func (o *OltpInf) getReadiness(c *gin.Context) {
+	probe(c, map[string]probeCheck{
+		"capabilities": o.checkCapabilities(),
+		"restore":      o.checkRestore(),
+		"policies":     o.checkPolicies(),
+	})
+}
+
+func (o *OltpInf) checkCapabilities() probeCheck {
+	var missing []string
+	for _, name := range o.distributions.Names() {
+		if d, err := o.distributions.Get(name); err != nil || d.Capabilities == nil {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return probeCheck{Status: checkFailed, Message: "capabilities not loaded: " + strings.Join(missing, ", ")}
+	}
+	return probeCheck{Status: checkOK}
+}
+
+// checkRestore fails until the persisted policies are restored, and when some
+// of them could not be restored and have not been applied since
+func (o *OltpInf) checkRestore() probeCheck {
+	if o.conf.DataDir == "" {
+		return probeCheck{Status: checkOK, Message: "persistence disabled"}
+	}
+	if !o.restored {
+		return probeCheck{Status: checkFailed, Message: "policies not restored yet"}
+	}
+	var failed []string
+	for policy, reason := range o.restoreErrors {
+		if !o.policies.exists(policy) {
+			failed = append(failed, policy+": "+reason)
+		}
+	}
+	sort.Strings(failed)
+	return o.failedPolicies("could not be restored", failed)
+}
+
+// checkPolicies fails when applied policies are failed or crash looping
+func (o *OltpInf) checkPolicies() probeCheck {
+	var failed []string
+	for _, policy := range o.policies.names() {
+		info, ok := o.policies.get(policy)
+		if !ok {
+			continue
+		}
+		switch s := info.Instance.GetStatus().StatusText; s {
+		case "failed", "crash_loop":
+			failed = append(failed, policy+": "+s)
+		}
+	}
+	return o.failedPolicies("are not running", failed)
+}
+
+// failedPolicies returns the check of failed policies, which passes when there
+// are none or when otlpinf is configured to be ready with failed policies
+func (o *OltpInf) failedPolicies(what string, failed []string) probeCheck {
+	if len(failed) == 0 {
+		return probeCheck{Status: checkOK}
+	}
+	msg := "policies " + what + ": " + strings.Join(failed, "; ")
+	if o.conf.ReadyWithFailedPolicies {
+		return probeCheck{Status: checkOK, Message: msg}
+	}
+	return probeCheck{Status: checkFailed, Message: msg}
+}
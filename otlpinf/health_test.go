@@ -0,0 +1,94 @@
+package otlpinf
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/netboxlabs/opentelemetry-infinity/config"
+	"github.com/netboxlabs/opentelemetry-infinity/store"
+)
+
+func getProbe(t *testing.T, o *OltpInf, path string) (int, probeResult) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", path, nil)
+	o.router.ServeHTTP(w, req)
+	var ret probeResult
+	if err := json.Unmarshal(w.Body.Bytes(), &ret); err != nil {
+		t.Fatalf("unexpected body %s: %v", w.Body.String(), err)
+	}
+	return w.Code, ret
+}
+
+// healthz reports 200 while otlpinf runs and 503 once it is shutting down.
+func TestHealthz(t *testing.T) {
+	o := newTestOtlp()
+	ctx, cancel := context.WithCancel(context.Background())
+	o.ctx = ctx
+
+	if code, ret := getProbe(t, o, "/healthz"); code != http.StatusOK || ret.Status != checkOK || ret.Checks["server"].Status != checkOK {
+		t.Errorf("expected otlpinf to be alive, got %d: %+v", code, ret)
+	}
+	cancel()
+	if code, ret := getProbe(t, o, "/healthz"); code != http.StatusServiceUnavailable || ret.Checks["server"].Status != checkFailed {
+		t.Errorf("expected otlpinf to be shutting down, got %d: %+v", code, ret)
+	}
+}
+
+// readyz requires loaded capabilities, restored policies and no failed policy, unless failed policies are allowed.
+func TestReadyz(t *testing.T) {
+	o := newUpdateTestOtlp(t)
+	code, ret := getProbe(t, o, "/readyz")
+	if code != http.StatusServiceUnavailable || ret.Checks["capabilities"].Status != checkFailed {
+		t.Fatalf("expected capabilities not to be loaded, got %d: %+v", code, ret)
+	}
+	o.distributions.Default().Capabilities = []byte("buildinfo:\n  version: 1.2.3\n")
+	if code, ret = getProbe(t, o, "/readyz"); code != http.StatusOK || ret.Status != checkOK {
+		t.Fatalf("expected otlpinf to be ready, got %d: %+v", code, ret)
+	}
+
+	dataDir := t.TempDir()
+	o.conf.DataDir = dataDir
+	if code, ret = getProbe(t, o, "/readyz"); code != http.StatusServiceUnavailable || ret.Checks["restore"].Status != checkFailed {
+		t.Fatalf("expected policies not to be restored yet, got %d: %+v", code, ret)
+	}
+	s, err := store.New(dataDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	o.store = s
+	if err = s.Save("p1", store.Record{Policy: config.Policy{Exporters: map[string]interface{}{"broken": nil}}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = o.restorePolicies(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	code, ret = getProbe(t, o, "/readyz")
+	if code != http.StatusServiceUnavailable || !strings.Contains(ret.Checks["restore"].Message, "p1") {
+		t.Fatalf("expected p1 to fail the readiness, got %d: %+v", code, ret)
+	}
+	if ret.Checks["capabilities"].Status != checkOK || ret.Checks["policies"].Status != checkOK {
+		t.Errorf("expected only the restore check to fail, got %+v", ret.Checks)
+	}
+
+	o.conf.ReadyWithFailedPolicies = true
+	if code, ret = getProbe(t, o, "/readyz"); code != http.StatusOK || !strings.Contains(ret.Checks["restore"].Message, "p1") {
+		t.Errorf("expected failed policies to be allowed, got %d: %+v", code, ret)
+	}
+	o.conf.ReadyWithFailedPolicies = false
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", PoliciesAPI, strings.NewReader("p1:\n  exporters:\n    debug:\n"))
+	req.Header.Set("Content-Type", HTTPYamlContent)
+	o.router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if code, ret = getProbe(t, o, "/readyz"); code != http.StatusOK {
+		t.Errorf("expected otlpinf to be ready once p1 is applied, got %d: %+v", code, ret)
+	}
+}
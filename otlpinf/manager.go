@@ -0,0 +1,204 @@
+package otlpinf
+
+import (
+	"sort"
+	"sync"
+)
+
+// policyManager holds the applied policies together with their revision
+// history and the policy files managing them. Its methods are safe for
+// concurrent use.
+//
+// Reads never wait for policy operations in progress: they see the policy as
+// it was before the operation until the operation applies its result.
+// Operations changing a policy are serialized with lock, so that the same
+// policy is never created, updated or deleted by two operations at once,
+// while operations on other policies proceed concurrently.
+type policyManager struct {
+	mu        sync.RWMutex
+	policies  map[string]RunnerInfo
+	revisions map[string]*revisionLog
+	managed   map[string]string
+
+	opsMu sync.Mutex
+	ops   map[string]*opLock
+}
+
+// opLock serializes the operations on a policy. It is removed from the
+// manager once no operation holds or waits for it.
+type opLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func newPolicyManager() *policyManager {
+	return &policyManager{
+		policies:  make(map[string]RunnerInfo),
+		revisions: make(map[string]*revisionLog),
+		managed:   make(map[string]string),
+		ops:       make(map[string]*opLock),
+	}
+}
+
+// lock waits until no other operation holds any of the policies and holds
+// them until the returned function is called. Policies are locked in name
+// order so that operations on overlapping policies cannot deadlock.
+func (m *policyManager) lock(names ...string) func() {
+	names = append([]string(nil), names...)
+	sort.Strings(names)
+	var held []string
+	for i, name := range names {
+		if i > 0 && name == names[i-1] {
+			continue
+		}
+		m.opsMu.Lock()
+		l, ok := m.ops[name]
+		if !ok {
+			l = &opLock{}
+			m.ops[name] = l
+		}
+		l.refs++
+		m.opsMu.Unlock()
+		l.mu.Lock()
+		held = append(held, name)
+	}
+
+	return sync.OnceFunc(func() {
+		m.opsMu.Lock()
+		defer m.opsMu.Unlock()
+		for _, name := range held {
+			l := m.ops[name]
+			l.mu.Unlock()
+			if l.refs--; l.refs == 0 {
+				delete(m.ops, name)
+			}
+		}
+	})
+}
+
+// get returns an applied policy
+func (m *policyManager) get(name string) (RunnerInfo, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	info, ok := m.policies[name]
+	return info, ok
+}
+
+// exists reports whether a policy is applied
+func (m *policyManager) exists(name string) bool {
+	_, ok := m.get(name)
+	return ok
+}
+
+// names returns the names of the applied policies in order
+func (m *policyManager) names() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	names := make([]string, 0, len(m.policies))
+	for name := range m.policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// all returns a copy of the applied policies
+func (m *policyManager) all() map[string]RunnerInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	policies := make(map[string]RunnerInfo, len(m.policies))
+	for name, info := range m.policies {
+		policies[name] = info
+	}
+	return policies
+}
+
+// set applies a policy, replacing any previous runner of it
+func (m *policyManager) set(name string, info RunnerInfo) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.policies[name] = info
+}
+
+// remove drops a policy together with its revision history and policy file,
+// returning the policy that was applied
+func (m *policyManager) remove(name string) (RunnerInfo, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	info, ok := m.policies[name]
+	delete(m.policies, name)
+	delete(m.revisions, name)
+	delete(m.managed, name)
+	return info, ok
+}
+
+// managedBy returns the policy file managing a policy, if any
+func (m *policyManager) managedBy(name string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	file, ok := m.managed[name]
+	return file, ok
+}
+
+// setManaged records the policy file managing a policy, or that the policy
+// is no longer managed when file is empty
+func (m *policyManager) setManaged(name string, file string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if file == "" {
+		delete(m.managed, name)
+		return
+	}
+	m.managed[name] = file
+}
+
+// managedPolicies returns the managed policies with their policy file
+func (m *policyManager) managedPolicies() map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	managed := make(map[string]string, len(m.managed))
+	for name, file := range m.managed {
+		managed[name] = file
+	}
+	return managed
+}
+
+// addRevision adds a revision to the history of a policy, keeping the last
+// limit revisions. It returns false, and drops the history, if the policy is
+// no longer applied.
+func (m *policyManager) addRevision(name string, rev Revision, limit int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.policies[name]; !ok {
+		delete(m.revisions, name)
+		return false
+	}
+	l, ok := m.revisions[name]
+	if !ok {
+		l = &revisionLog{}
+		m.revisions[name] = l
+	}
+	l.add(rev, limit)
+	return true
+}
+
+// revisionHistory returns the revisions of a policy, oldest first
+func (m *policyManager) revisionHistory(name string) []Revision {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	revisions := []Revision{}
+	if l, ok := m.revisions[name]; ok {
+		revisions = append(revisions, l.revisions...)
+	}
+	return revisions
+}
+
+// revision returns a revision of a policy by number
+func (m *policyManager) revision(name string, number int) (Revision, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if l, ok := m.revisions[name]; ok {
+		return l.get(number)
+	}
+	return Revision{}, false
+}
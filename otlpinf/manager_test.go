@@ -0,0 +1,146 @@
+package otlpinf
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Operations on a policy wait for each other, operations on other policies do not
+func TestPolicyManagerLock(t *testing.T) {
+	m := newPolicyManager()
+	unlock := m.lock("a")
+
+	other := make(chan struct{})
+	go func() {
+		m.lock("b", "b")()
+		close(other)
+	}()
+	select {
+	case <-other:
+	case <-time.After(time.Second):
+		t.Fatalf("expected a lock on another policy not to wait")
+	}
+
+	same := make(chan struct{})
+	go func() {
+		m.lock("b", "a")()
+		close(same)
+	}()
+	select {
+	case <-same:
+		t.Fatalf("expected a lock on the same policy to wait")
+	case <-time.After(100 * time.Millisecond):
+	}
+	unlock()
+	unlock()
+	select {
+	case <-same:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the lock to be acquired once released")
+	}
+	if len(m.ops) != 0 {
+		t.Errorf("expected released locks to be dropped, got %v", m.ops)
+	}
+}
+
+// The manager can be used by many goroutines at once
+func TestPolicyManagerConcurrency(t *testing.T) {
+	m := newPolicyManager()
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("p%d", i%4)
+			for j := 0; j < 200; j++ {
+				unlock := m.lock(name, "shared")
+				m.set(name, RunnerInfo{})
+				m.setManaged(name, "a.yaml")
+				m.addRevision(name, Revision{Change: "updated"}, 3)
+				unlock()
+				m.get(name)
+				m.names()
+				m.all()
+				m.managedPolicies()
+				m.revisionHistory(name)
+				if j%10 == 0 {
+					m.remove(name)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, name := range m.names() {
+		if n := len(m.revisionHistory(name)); n > 3 {
+			t.Errorf("expected at most 3 revisions of %s, got %d", name, n)
+		}
+	}
+}
+
+// Concurrent requests creating, reading and deleting policies and reading the status are all answered consistently
+func TestConcurrentPolicyRequests(t *testing.T) {
+	o := newUpdateTestOtlp(t)
+	var wg sync.WaitGroup
+	errs := make(chan string, 256)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			policy := fmt.Sprintf("p%d", i%2)
+			for j := 0; j < 3; j++ {
+				body := policy + ":\n  exporters:\n    debug: {}\n"
+				req, _ := http.NewRequest(http.MethodPost, PoliciesAPI, strings.NewReader(body))
+				req.Header.Set("Content-Type", HTTPYamlContent)
+				w := httptest.NewRecorder()
+				o.router.ServeHTTP(w, req)
+				if w.Code != http.StatusCreated && w.Code != http.StatusConflict {
+					errs <- fmt.Sprintf("create %s: %d %s", policy, w.Code, w.Body.String())
+				}
+
+				w = httptest.NewRecorder()
+				req, _ = http.NewRequest(http.MethodGet, PoliciesAPI, nil)
+				o.router.ServeHTTP(w, req)
+				if w.Code != http.StatusOK {
+					errs <- fmt.Sprintf("list: %d %s", w.Code, w.Body.String())
+				}
+
+				w = httptest.NewRecorder()
+				req, _ = http.NewRequest(http.MethodDelete, PoliciesAPI+"/"+policy, nil)
+				o.router.ServeHTTP(w, req)
+				if w.Code != http.StatusOK && w.Code != http.StatusNotFound {
+					errs <- fmt.Sprintf("delete %s: %d %s", policy, w.Code, w.Body.String())
+				}
+			}
+		}(i)
+	}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, StatusAPI, nil)
+				o.router.ServeHTTP(w, req)
+				if w.Code != http.StatusOK {
+					errs <- fmt.Sprintf("status: %d %s", w.Code, w.Body.String())
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	for _, policy := range o.policies.names() {
+		info, _ := o.policies.get(policy)
+		if s := info.Instance.GetStatus(); s.StatusText != "running" {
+			t.Errorf("expected the remaining policy %s to be running, got %q", policy, s.StatusText)
+		}
+	}
+}
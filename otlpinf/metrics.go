@@ -0,0 +1,124 @@
+package otlpinf
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "otlpinf"
+
+var (
+	policiesDesc = prometheus.NewDesc(metricsNamespace+"_policies",
+		"Number of applied policies by runner status.", []string{"status"}, nil)
+	restartsDesc = prometheus.NewDesc(metricsNamespace+"_policy_restarts_total",
+		"Number of times the collector of a policy was restarted.", []string{"policy"}, nil)
+	uptimeDesc = prometheus.NewDesc(metricsNamespace+"_policy_uptime_seconds",
+		"Time the collector of a policy has been running since it was last launched.", []string{"policy"}, nil)
+	logLinesDesc = prometheus.NewDesc(metricsNamespace+"_collector_log_lines_total",
+		"Number of collector log lines of a policy by level.", []string{"policy", "level"}, nil)
+)
+
+// metrics holds the Prometheus metrics of otlpinf, served on /metrics
+type metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	launchDuration  *prometheus.HistogramVec
+}
+
+func newMetrics(o *OltpInf) *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_requests_total",
+			Help:      "Number of REST API requests by method, route and status code.",
+		}, []string{"method", "route", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of REST API requests by method, route and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "code"}),
+		launchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "runner_start_duration_seconds",
+			Help:      "Time collectors took to become ready, or to fail, when launched.",
+			Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		}, []string{"result"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.launchDuration,
+		policyCollector{o},
+	)
+	return m
+}
+
+// instrument counts and times the requests of the router. Requests that match
+// no route are counted under an empty route to bound the number of series.
+func (m *metrics) instrument(c *gin.Context) {
+	begin := time.Now()
+	c.Next()
+	labels := prometheus.Labels{
+		"method": c.Request.Method,
+		"route":  c.FullPath(),
+		"code":   strconv.Itoa(c.Writer.Status()),
+	}
+	m.requests.With(labels).Inc()
+	m.requestDuration.With(labels).Observe(time.Since(begin).Seconds())
+}
+
+// observeLaunch records the launch of a collector
+func (m *metrics) observeLaunch(startup time.Duration, err error) {
+	result := "ready"
+	if err != nil {
+		result = "failed"
+	}
+	m.launchDuration.WithLabelValues(result).Observe(startup.Seconds())
+}
+
+func (m *metrics) handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+}
+
+// policyCollector reports the state of the applied policies when scraped, so
+// that the series of deleted policies disappear with them
+type policyCollector struct {
+	o *OltpInf
+}
+
+func (p policyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- policiesDesc
+	ch <- restartsDesc
+	ch <- uptimeDesc
+	ch <- logLinesDesc
+}
+
+func (p policyCollector) Collect(ch chan<- prometheus.Metric) {
+	statuses := make(map[string]int)
+	for policy, info := range p.o.policies.all() {
+		s := info.Instance.GetStatus()
+		status := s.StatusText
+		if status == "" {
+			status = "unknown"
+		}
+		statuses[status]++
+		ch <- prometheus.MustNewConstMetric(restartsDesc, prometheus.CounterValue, float64(s.RestartCount), policy)
+		ch <- prometheus.MustNewConstMetric(uptimeDesc, prometheus.GaugeValue, info.Instance.Uptime().Seconds(), policy)
+		for level, n := range info.Instance.LogCounts() {
+			ch <- prometheus.MustNewConstMetric(logLinesDesc, prometheus.CounterValue, float64(n), policy, level)
+		}
+	}
+	for status, n := range statuses {
+		ch <- prometheus.MustNewConstMetric(policiesDesc, prometheus.GaugeValue, float64(n), status)
+	}
+}
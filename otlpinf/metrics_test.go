@@ -0,0 +1,46 @@
+package otlpinf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// /metrics reports the policies, collector launches, log lines and API requests.
+func TestMetrics(t *testing.T) {
+	o := newUpdateTestOtlp(t)
+	policy := "receivers:\n    otlp:\n  exporters:\n    debug:\n  service:\n    pipelines:\n      metrics:\n        receivers: [otlp]\n        exporters: [debug]\n"
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", PoliciesAPI, strings.NewReader("p1:\n  "+policy))
+	req.Header.Set("Content-Type", HTTPYamlContent)
+	o.router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	broken := strings.Replace(policy, "debug:\n", "debug:\n    broken:\n", 1)
+	putPolicy(o, "p1", "p1:\n  "+broken)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/metrics", nil)
+	o.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`otlpinf_policies{status="running"} 1`,
+		`otlpinf_policy_restarts_total{policy="p1"} 0`,
+		`otlpinf_policy_uptime_seconds{policy="p1"}`,
+		`otlpinf_collector_log_lines_total{level="info",policy="p1"}`,
+		`otlpinf_runner_start_duration_seconds_count{result="ready"} 2`,
+		`otlpinf_runner_start_duration_seconds_count{result="failed"} 1`,
+		`otlpinf_http_requests_total{code="201",method="POST",route="/api/v1/policies"} 1`,
+		`otlpinf_http_request_duration_seconds_count{code="400",method="PUT",route="/api/v1/policies/:policy"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %s in the metrics", want)
+		}
+	}
+}
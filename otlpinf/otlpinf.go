@@ -6,13 +6,16 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"gopkg.in/yaml.v3"
 
+	"github.com/netboxlabs/opentelemetry-infinity/audit"
+	"github.com/netboxlabs/opentelemetry-infinity/auth"
 	"github.com/netboxlabs/opentelemetry-infinity/config"
 	"github.com/netboxlabs/opentelemetry-infinity/runner"
+	"github.com/netboxlabs/opentelemetry-infinity/store"
 )
 
 const routineKey config.ContextKey = "routine"
@@ -28,18 +31,31 @@ type OltpInf struct {
 	logger         *slog.Logger
 	conf           *config.Config
 	stat           config.Status
-	policies       map[string]RunnerInfo
+	policies       *policyManager
+	stopWatch      func()
 	policiesDir    string
 	ctx            context.Context
 	cancelFunction context.CancelFunc
 	router         *gin.Engine
-	capabilities   []byte
+	auth           auth.Chain
+	authz          *auth.Authorizer
+	audit          *audit.Log
+	metrics        *metrics
+	stopTelemetry  func(context.Context) error
+	distributions  *runner.Registry
+	store          *store.Store
 	httpServer     *http.Server
+
+	// set by Start before the server is started
+	restored      bool
+	restoreErrors map[string]string
 }
 
 // NewOtlp creates a new otlpinf routine
 func NewOtlp(logger *slog.Logger, c *config.Config) *OltpInf {
-	return &OltpInf{logger: logger, conf: c, policies: make(map[string]RunnerInfo)}
+	o := &OltpInf{logger: logger, conf: c, policies: newPolicyManager(), distributions: runner.NewRegistry(c)}
+	o.metrics = newMetrics(o)
+	return o
 }
 
 // Start starts the otlpinf routine
@@ -53,26 +69,52 @@ func (o *OltpInf) Start(ctx context.Context, cancelFunc context.CancelFunc) <-ch
 	if err != nil {
 		return o.startFailure(err)
 	}
-	o.capabilities, err = runner.GetCapabilities(o.logger)
-	if err != nil {
+	if err = o.distributions.Load(o.logger); err != nil {
 		return o.startFailure(err)
 	}
-	s := struct {
-		Buildinfo struct {
-			Version string
-		}
-	}{}
-	err = yaml.Unmarshal(o.capabilities, &s)
-	if err != nil {
+	o.stat.Version = o.distributions.Default().Version
+	o.stat.Distributions = o.distributions.Versions()
+	if err = o.startTelemetry(o.ctx); err != nil {
+		return o.startFailure(err)
+	}
+	if o.auth, err = auth.New(o.conf); err != nil {
 		return o.startFailure(err)
 	}
-	o.stat.Version = s.Buildinfo.Version
+	if o.conf.AuthzFile != "" {
+		if len(o.auth) == 0 {
+			return o.startFailure(errors.New("authorization requires an authentication method"))
+		}
+		if o.authz, err = auth.NewAuthorizer(o.conf.AuthzFile); err != nil {
+			return o.startFailure(err)
+		}
+	}
+	if o.conf.AuditFile != "" {
+		maxSize := int64(o.conf.AuditMaxSize) * 1024 * 1024
+		if o.audit, err = audit.New(o.conf.AuditFile, maxSize, o.conf.AuditMaxFiles); err != nil {
+			return o.startFailure(err)
+		}
+	}
+
+	if o.conf.DataDir != "" {
+		if o.store, err = store.New(o.conf.DataDir); err != nil {
+			return o.startFailure(err)
+		}
+		if err = o.restorePolicies(); err != nil {
+			return o.startFailure(err)
+		}
+	}
+	if o.conf.PoliciesFrom != "" {
+		o.reconcilePolicyDir()
+		if err = o.watchPolicyDir(); err != nil {
+			return o.startFailure(err)
+		}
+	}
 
 	return o.startServer()
 }
 
 // Stop stops the otlpinf routine
-func (o *OltpInf) Stop(_ context.Context) {
+func (o *OltpInf) Stop(ctx context.Context) {
 	if o.httpServer != nil {
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
@@ -82,12 +124,37 @@ func (o *OltpInf) Stop(_ context.Context) {
 		}
 		o.httpServer = nil
 	}
+	if o.stopWatch != nil {
+		o.stopWatch()
+		o.stopWatch = nil
+	}
+	var wg sync.WaitGroup
+	for _, info := range o.policies.all() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			info.Instance.Stop(ctx)
+		}()
+	}
+	wg.Wait()
 	if o.policiesDir != "" {
 		if err := os.RemoveAll(o.policiesDir); err != nil {
 			o.logger.Error("error removing policies directory", "error", err)
 		}
 		o.policiesDir = ""
 	}
+	if o.stopTelemetry != nil {
+		if err := o.stopTelemetry(ctx); err != nil {
+			o.logger.Error("error flushing telemetry", "error", err)
+		}
+		o.stopTelemetry = nil
+	}
+	if o.audit != nil {
+		if err := o.audit.Close(); err != nil {
+			o.logger.Error("error closing audit log", "error", err)
+		}
+		o.audit = nil
+	}
 	if o.cancelFunction != nil {
 		o.cancelFunction()
 	}
@@ -22,6 +22,7 @@ import (
 const (
 	TestHost        = "localhost"
 	PoliciesAPI     = "/api/v1/policies"
+	StatusAPI       = "/api/v1/status"
 	HTTPYamlContent = "application/x-yaml"
 )
 
@@ -0,0 +1,70 @@
+package otlpinf
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	yson "github.com/ghodss/yaml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/netboxlabs/opentelemetry-infinity/config"
+)
+
+const (
+	mergePatchContent = "application/merge-patch+json"
+	jsonPatchContent  = "application/json-patch+json"
+	yamlContent       = "application/x-yaml"
+)
+
+var errPatchContentType = errors.New("invalid Content-Type. Only '" + mergePatchContent + "', '" + jsonPatchContent +
+	"' and '" + yamlContent + "' (merge patch) are supported")
+
+// applyPolicyPatch applies a patch to a policy. JSON merge patches (RFC 7396) are
+// accepted as JSON or YAML and JSON patches (RFC 6902) as JSON.
+func applyPolicyPatch(p config.Policy, contentType string, patch []byte) (config.Policy, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case mergePatchContent, jsonPatchContent:
+	case yamlContent:
+		var err error
+		if patch, err = yson.YAMLToJSON(patch); err != nil {
+			return config.Policy{}, err
+		}
+		mediaType = mergePatchContent
+	default:
+		return config.Policy{}, errPatchContentType
+	}
+
+	y, err := yaml.Marshal(&p)
+	if err != nil {
+		return config.Policy{}, err
+	}
+	doc, err := yson.YAMLToJSON(y)
+	if err != nil {
+		return config.Policy{}, err
+	}
+
+	if mediaType == mergePatchContent {
+		doc, err = jsonpatch.MergePatch(doc, patch)
+	} else {
+		var ops jsonpatch.Patch
+		if ops, err = jsonpatch.DecodePatch(patch); err == nil {
+			doc, err = ops.Apply(doc)
+		}
+	}
+	if err != nil {
+		return config.Policy{}, err
+	}
+
+	// Unknown fields are rejected so that a misspelled path does not
+	// silently leave the policy unchanged
+	var patched config.Policy
+	dec := yaml.NewDecoder(bytes.NewReader(doc))
+	dec.KnownFields(true)
+	if err = dec.Decode(&patched); err != nil {
+		return config.Policy{}, err
+	}
+	return patched, nil
+}
@@ -0,0 +1,86 @@
+package otlpinf
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/netboxlabs/opentelemetry-infinity/config"
+)
+
+func patchTestPolicy() config.Policy {
+	return config.Policy{
+		Receivers: map[string]interface{}{"otlp": nil},
+		Exporters: map[string]interface{}{"otlphttp": map[string]interface{}{"endpoint": "http://old:4318"}},
+		Service: map[string]interface{}{"pipelines": map[string]interface{}{
+			"metrics": map[string]interface{}{"receivers": []interface{}{"otlp"}, "exporters": []interface{}{"otlphttp"}},
+		}},
+		Otlpinf: config.PolicyOptions{DrainTimeout: 30 * time.Second},
+	}
+}
+
+// applyPolicyPatch applies merge patches given as JSON or YAML.
+func TestApplyPolicyMergePatch(t *testing.T) {
+	patches := map[string]string{
+		mergePatchContent:                     `{"exporters":{"otlphttp":{"endpoint":"http://new:4318"}},"processors":{"batch":{}}}`,
+		yamlContent:                           "exporters:\n  otlphttp:\n    endpoint: http://new:4318\nprocessors:\n  batch: {}\n",
+		mergePatchContent + "; charset=utf-8": `{"exporters":{"otlphttp":{"endpoint":"http://new:4318"}}}`,
+	}
+	for contentType, patch := range patches {
+		p, err := applyPolicyPatch(patchTestPolicy(), contentType, []byte(patch))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", contentType, err)
+		}
+		if endpoint := p.Exporters["otlphttp"].(map[string]interface{})["endpoint"]; endpoint != "http://new:4318" {
+			t.Errorf("%s: expected the endpoint to be replaced, got %v", contentType, endpoint)
+		}
+		if _, ok := p.Receivers["otlp"]; !ok {
+			t.Errorf("%s: expected untouched receivers to be kept, got %v", contentType, p.Receivers)
+		}
+		if p.Otlpinf.DrainTimeout != 30*time.Second {
+			t.Errorf("%s: expected otlpinf options to be kept, got %+v", contentType, p.Otlpinf)
+		}
+	}
+}
+
+// applyPolicyPatch applies JSON patch operations in order.
+func TestApplyPolicyJSONPatch(t *testing.T) {
+	patch := `[
+		{"op": "add", "path": "/processors", "value": {"batch": null}},
+		{"op": "add", "path": "/service/pipelines/metrics/processors", "value": ["batch"]},
+		{"op": "test", "path": "/exporters/otlphttp/endpoint", "value": "http://old:4318"}
+	]`
+	p, err := applyPolicyPatch(patchTestPolicy(), jsonPatchContent, []byte(patch))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	metrics := p.Service["pipelines"].(map[string]interface{})["metrics"].(map[string]interface{})
+	if processors, ok := metrics["processors"].([]interface{}); !ok || len(processors) != 1 || processors[0] != "batch" {
+		t.Errorf("expected the batch processor in the pipeline, got %v", metrics)
+	}
+	if _, ok := p.Processors["batch"]; !ok {
+		t.Errorf("expected the batch processor to be defined, got %v", p.Processors)
+	}
+}
+
+// applyPolicyPatch rejects unsupported content types, failing operations and unknown fields.
+func TestApplyPolicyPatchErrors(t *testing.T) {
+	if _, err := applyPolicyPatch(patchTestPolicy(), "application/json", []byte(`{}`)); !errors.Is(err, errPatchContentType) {
+		t.Errorf("expected a content type error, got %v", err)
+	}
+	cases := []struct {
+		contentType string
+		patch       string
+	}{
+		{jsonPatchContent, `[{"op": "test", "path": "/exporters/otlphttp/endpoint", "value": "http://new:4318"}]`},
+		{jsonPatchContent, `[{"op": "remove", "path": "/exporters/missing"}]`},
+		{jsonPatchContent, `{"op": "add"}`},
+		{mergePatchContent, `{"recievers": {"otlp": null}}`},
+		{mergePatchContent, `{`},
+	}
+	for _, tc := range cases {
+		if _, err := applyPolicyPatch(patchTestPolicy(), tc.contentType, []byte(tc.patch)); err == nil {
+			t.Errorf("%s %s: expected an error", tc.contentType, tc.patch)
+		}
+	}
+}
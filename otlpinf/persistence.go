@@ -0,0 +1,69 @@
+package otlpinf
+
+import (
+	"sort"
+	"time"
+
+	"github.com/netboxlabs/opentelemetry-infinity/store"
+)
+
+const restoredBy = "otlpinf"
+
+// savePolicy records the current state of a policy in the store, removing its
+// record when the policy no longer exists or is managed by the policy
+// directory. Store failures are logged as the
+// policy change itself has already been applied.
+func (o *OltpInf) savePolicy(policy string, changedBy string) {
+	if o.store == nil {
+		return
+	}
+	info, ok := o.policies.get(policy)
+	if _, managed := o.policies.managedBy(policy); managed {
+		ok = false
+	}
+	var err error
+	if ok {
+		err = o.store.Save(policy, store.Record{Policy: info.Policy, UpdatedAt: time.Now(), ChangedBy: changedBy})
+	} else {
+		err = o.store.Delete(policy)
+	}
+	if err != nil {
+		o.logger.Error("failed to persist policy", "policy", policy, "error", err)
+	}
+}
+
+// restorePolicies starts the policies recorded in the store. Policies that
+// fail to start are logged, reported by /readyz and kept in the store, so
+// that they are retried on the next start.
+func (o *OltpInf) restorePolicies() error {
+	records, err := o.store.Load()
+	if err != nil {
+		return err
+	}
+	o.restoreErrors = make(map[string]string)
+	names := make([]string, 0, len(records))
+	for name := range records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, policy := range names {
+		rec := records[policy]
+		r, err := o.configureRunner(policy, rec.Policy)
+		if err == nil {
+			if err = o.startRunner(o.ctx, policy, r); err != nil {
+				o.discardRunner(r)
+			}
+		}
+		if err != nil {
+			o.logger.Error("failed to restore policy", "policy", policy, "error", err)
+			o.restoreErrors[policy] = err.Error()
+			continue
+		}
+		o.policies.set(policy, RunnerInfo{Policy: rec.Policy, Instance: r})
+		o.recordRevision(policy, restoredBy, Revision{Change: "restored", Policy: rec.Policy}, updateResult{Result: "applied"})
+		o.logger.Info("policy restored", "policy", policy, "updated_at", rec.UpdatedAt, "changed_by", rec.ChangedBy)
+	}
+	o.restored = true
+	return nil
+}
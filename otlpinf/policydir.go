@@ -0,0 +1,216 @@
+package otlpinf
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+
+	"github.com/netboxlabs/opentelemetry-infinity/audit"
+	"github.com/netboxlabs/opentelemetry-infinity/config"
+)
+
+const (
+	policyFileExt       = ".yaml"
+	policyDirDebounce   = 500 * time.Millisecond
+	policyFileChangedBy = "policies_from:"
+)
+
+// loadPolicyDir reads the policies of every YAML file in a directory, which
+// hold policies by name like the body of POST /api/v1/policies. It returns the
+// policies and the file each of them is defined in.
+func loadPolicyDir(dir string) (map[string]config.Policy, map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	policies := make(map[string]config.Policy)
+	files := make(map[string]string)
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != policyFileExt {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, nil, err
+		}
+		var payload map[string]config.Policy
+		if err = yaml.Unmarshal(b, &payload); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		for policy, data := range payload {
+			if other, ok := files[policy]; ok {
+				return nil, nil, fmt.Errorf("policy '%s' is defined in both %s and %s", policy, other, name)
+			}
+			policies[policy] = data
+			files[policy] = name
+		}
+	}
+	return policies, files, nil
+}
+
+// reconcilePolicyDir makes the applied policies match the policy directory:
+// policies of new files are created, policies whose file changed are updated
+// and policies whose file was removed are deleted. An API created policy with
+// the name of a policy in the directory is taken over by the directory. The
+// directory is left unapplied if any of its files cannot be read.
+func (o *OltpInf) reconcilePolicyDir() {
+	policies, files, err := loadPolicyDir(o.conf.PoliciesFrom)
+	if err != nil {
+		o.logger.Error("failed to load policy directory", "dir", o.conf.PoliciesFrom, "error", err)
+		return
+	}
+
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, policy := range names {
+		o.applyPolicyFile(policy, files[policy], policies[policy])
+	}
+
+	for policy, file := range o.policies.managedPolicies() {
+		if _, ok := policies[policy]; !ok {
+			o.removePolicyFile(policy, file)
+		}
+	}
+}
+
+func (o *OltpInf) applyPolicyFile(policy string, file string, data config.Policy) {
+	unlock := o.policies.lock(policy)
+	defer unlock()
+	changedBy := policyFileChangedBy + file
+	current, exists := o.policies.get(policy)
+	if exists && reflect.DeepEqual(current.Policy, data) {
+		if managedBy, _ := o.policies.managedBy(policy); managedBy != file {
+			o.policies.setManaged(policy, file)
+			o.savePolicy(policy, changedBy)
+		}
+		return
+	}
+
+	action := "update"
+	if !exists {
+		action = "create"
+	}
+	runners, pErr := o.preparePolicies(o.ctx, map[string]config.Policy{policy: data})
+	if pErr != nil {
+		o.logger.Error("policy file rejected", "policy", policy, "file", file, "error", pErr)
+		o.auditPolicyFile(file, action, policy, "failed")
+		return
+	}
+	r := runners[policy]
+
+	if !exists {
+		o.policies.set(policy, RunnerInfo{Policy: data, Instance: r})
+		o.policies.setManaged(policy, file)
+		if err := o.startRunner(o.ctx, policy, r); err != nil {
+			o.policies.remove(policy)
+			o.discardRunner(r)
+			o.logger.Error("policy file could not be started", "policy", policy, "file", file, "error", err)
+			o.auditPolicyFile(file, action, policy, "failed")
+			return
+		}
+		o.recordRevision(policy, changedBy, Revision{Change: "created", Policy: data}, updateResult{Result: "applied"})
+		o.savePolicy(policy, changedBy)
+		o.auditPolicyFile(file, action, policy, "applied")
+		o.logger.Info("policy file applied", "policy", policy, "file", file)
+		return
+	}
+
+	_, result := o.replacePolicy(o.ctx, policy, current, RunnerInfo{Policy: data, Instance: r})
+	if o.policies.exists(policy) {
+		o.policies.setManaged(policy, file)
+	}
+	o.recordRevision(policy, changedBy, Revision{Change: "updated", Policy: data}, result)
+	o.savePolicy(policy, changedBy)
+	o.auditPolicyFile(file, action, policy, result.Result)
+	if result.Result != "applied" {
+		o.logger.Error("policy file could not be applied", "policy", policy, "file", file, "result", result.Result, "reason", result.Reason)
+	}
+}
+
+// removePolicyFile deletes a policy whose policy file was removed
+func (o *OltpInf) removePolicyFile(policy string, file string) {
+	unlock := o.policies.lock(policy)
+	defer unlock()
+	if info, ok := o.policies.remove(policy); ok {
+		o.stopRunner(info.Instance)
+		o.logger.Info("policy file removed, policy deleted", "policy", policy, "file", file)
+		o.auditPolicyFile(file, "delete", policy, "succeeded")
+	}
+	o.savePolicy(policy, policyFileChangedBy+file)
+}
+
+// watchPolicyDir reconciles the policy directory whenever its files change.
+// Changes are debounced so that a sync touching many files is applied once.
+func (o *OltpInf) watchPolicyDir() error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err = w.Add(o.conf.PoliciesFrom); err != nil {
+		_ = w.Close()
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(o.ctx)
+	done := make(chan struct{})
+	o.stopWatch = func() {
+		cancel()
+		<-done
+	}
+	go func() {
+		defer close(done)
+		defer func() { _ = w.Close() }()
+		var debounce <-chan time.Time
+		for {
+			select {
+			case _, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				debounce = time.After(policyDirDebounce)
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				o.logger.Warn("policy directory watch error", "dir", o.conf.PoliciesFrom, "error", err)
+			case <-debounce:
+				debounce = nil
+				o.reconcilePolicyDir()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// rejectManaged refuses to change a policy that is managed by the policy
+// directory. On refusal the error response has been written and true is
+// returned.
+func (o *OltpInf) rejectManaged(c *gin.Context, policy string) bool {
+	file, ok := o.policies.managedBy(policy)
+	if !ok {
+		return false
+	}
+	c.IndentedJSON(http.StatusForbidden, returnValue{"policy '" + policy + "' is managed by policy file '" + file + "' and is read-only"})
+	return true
+}
+
+// auditPolicyFile records a change of the policy directory in the audit log
+func (o *OltpInf) auditPolicyFile(file string, action string, policy string, outcome string) {
+	o.recordAudit(audit.Entry{Principal: policyFileChangedBy + file, Action: action, Policies: []string{policy}, Outcome: outcome})
+}
@@ -0,0 +1,117 @@
+package otlpinf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const policyDirTestPolicy = "  receivers:\n    otlp:\n  exporters:\n    debug:\n  service:\n    pipelines:\n      metrics:\n        receivers: [otlp]\n        exporters: [debug]\n"
+
+func writePolicyFile(t *testing.T, dir string, name string, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// loadPolicyDir reads the YAML files of a directory and rejects policies defined twice.
+func TestLoadPolicyDir(t *testing.T) {
+	dir := t.TempDir()
+	writePolicyFile(t, dir, "a.yaml", "p1:\n"+policyDirTestPolicy+"p2:\n"+policyDirTestPolicy)
+	writePolicyFile(t, dir, "b.yaml", "p3:\n"+policyDirTestPolicy)
+	writePolicyFile(t, dir, ".hidden.yaml", "invalid")
+	writePolicyFile(t, dir, "notes.txt", "invalid")
+
+	policies, files, err := loadPolicyDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(policies) != 3 || files["p1"] != "a.yaml" || files["p3"] != "b.yaml" {
+		t.Errorf("unexpected policies %v from files %v", policies, files)
+	}
+
+	writePolicyFile(t, dir, "c.yaml", "p1:\n"+policyDirTestPolicy)
+	if _, _, err = loadPolicyDir(dir); err == nil || !strings.Contains(err.Error(), "p1") {
+		t.Errorf("expected an error for the duplicate policy, got %v", err)
+	}
+}
+
+// reconcilePolicyDir creates, updates and deletes policies following their files, which are read-only in the API.
+func TestReconcilePolicyDir(t *testing.T) {
+	o := newUpdateTestOtlp(t)
+	dir := t.TempDir()
+	o.conf.PoliciesFrom = dir
+	o.policies.set("p2", RunnerInfo{})
+
+	writePolicyFile(t, dir, "a.yaml", "p1:\n"+policyDirTestPolicy)
+	o.reconcilePolicyDir()
+	info, ok := o.policies.get("p1")
+	if file, _ := o.policies.managedBy("p1"); !ok || info.Instance.GetStatus().StatusText != "running" || file != "a.yaml" {
+		t.Fatalf("expected p1 to be applied from a.yaml, got %+v, %v", info, o.policies.managedPolicies())
+	}
+	if _, ok = o.policies.managedBy("p2"); ok {
+		t.Errorf("expected the API policy p2 not to be managed")
+	}
+
+	for _, method := range []string{"PUT", "PATCH", "DELETE"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, PoliciesAPI+"/p1", strings.NewReader("{}"))
+		req.Header.Set("Content-Type", mergePatchContent)
+		o.router.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s: expected 403, got %d", method, w.Code)
+		}
+	}
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", PoliciesAPI+"/p1", nil)
+	o.router.ServeHTTP(w, req)
+	if !strings.Contains(w.Body.String(), "managed_by: a.yaml") {
+		t.Errorf("expected the policy to report its file, got %s", w.Body.String())
+	}
+
+	writePolicyFile(t, dir, "a.yaml", "p1:\n"+strings.Replace(policyDirTestPolicy, "debug", "debug/updated", -1))
+	o.reconcilePolicyDir()
+	if _, ok = appliedPolicy(o, "p1").Exporters["debug/updated"]; !ok {
+		t.Errorf("expected p1 to be updated, got %+v", appliedPolicy(o, "p1"))
+	}
+
+	if err := os.Remove(filepath.Join(dir, "a.yaml")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	o.reconcilePolicyDir()
+	if o.policies.exists("p1") {
+		t.Errorf("expected p1 to be deleted with its file")
+	}
+	if !o.policies.exists("p2") {
+		t.Errorf("expected the API policy p2 to be kept")
+	}
+	o.policies.remove("p2")
+}
+
+// watchPolicyDir applies files added to the directory.
+func TestWatchPolicyDir(t *testing.T) {
+	o := newUpdateTestOtlp(t)
+	dir := t.TempDir()
+	o.conf.PoliciesFrom = dir
+	if err := o.watchPolicyDir(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer o.stopWatch()
+
+	writePolicyFile(t, dir, "a.yaml", "p1:\n"+policyDirTestPolicy)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if o.policies.exists("p1") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected p1 to be applied after its file was added")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
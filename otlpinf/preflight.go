@@ -0,0 +1,138 @@
+package otlpinf
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/netboxlabs/opentelemetry-infinity/config"
+	"github.com/netboxlabs/opentelemetry-infinity/runner"
+)
+
+// preflightError is returned when policies fail the pre-flight checks, along
+// with the response the API returns for it
+type preflightError struct {
+	code int
+	body interface{}
+}
+
+func (e *preflightError) Error() string {
+	switch b := e.body.(type) {
+	case validationFailure:
+		return b.Message + ": " + strings.Join(b.Errors, "; ")
+	case returnValue:
+		return b.Message
+	default:
+		return fmt.Sprint(b)
+	}
+}
+
+// missingComponents lists the components of a policy that the distribution
+// does not provide
+func missingComponents(p config.Policy, d *runner.Distribution) []string {
+	sections := map[string]map[string]interface{}{
+		"receivers":  p.Receivers,
+		"processors": p.Processors,
+		"exporters":  p.Exporters,
+		"extensions": p.Extensions,
+	}
+	var missing []string
+	for kind, section := range sections {
+		for id := range section {
+			componentType, _, _ := strings.Cut(id, "/")
+			if !d.HasComponent(kind, componentType) {
+				missing = append(missing, fmt.Sprintf("%s %q is not available in distribution %q", strings.TrimSuffix(kind, "s"), id, d.Name))
+			}
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// listenPorts returns the ports explicitly configured as endpoints of the
+// receivers and extensions of a policy
+func listenPorts(p config.Policy) []int {
+	var ports []int
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			for k, child := range val {
+				if s, ok := child.(string); ok && k == "endpoint" {
+					if port, ok := endpointPort(s); ok {
+						ports = append(ports, port)
+					}
+					continue
+				}
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range val {
+				walk(child)
+			}
+		}
+	}
+	walk(map[string]interface{}(p.Receivers))
+	walk(map[string]interface{}(p.Extensions))
+	sort.Ints(ports)
+	return ports
+}
+
+func endpointPort(endpoint string) (int, bool) {
+	_, p, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return 0, false
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil || port == 0 {
+		return 0, false
+	}
+	return port, true
+}
+
+// portConflicts checks the endpoints of the new policies against each other,
+// against the applied policies and against ports already bound on the host
+func (o *OltpInf) portConflicts(payload map[string]config.Policy) []string {
+	owners := make(map[int]string)
+	for name, info := range o.policies.all() {
+		for _, port := range listenPorts(info.Policy) {
+			owners[port] = name
+		}
+	}
+
+	names := make([]string, 0, len(payload))
+	for name := range payload {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var conflicts []string
+	for _, name := range names {
+		for _, port := range listenPorts(payload[name]) {
+			if owner, ok := owners[port]; ok {
+				if owner != name {
+					conflicts = append(conflicts, fmt.Sprintf("policy '%s' port %d is already used by policy '%s'", name, port, owner))
+				}
+				continue
+			}
+			owners[port] = name
+			if portInUse(port) {
+				conflicts = append(conflicts, fmt.Sprintf("policy '%s' port %d is already in use", name, port))
+			}
+		}
+	}
+	return conflicts
+}
+
+func portInUse(port int) bool {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return errors.Is(err, syscall.EADDRINUSE)
+	}
+	_ = l.Close()
+	return false
+}
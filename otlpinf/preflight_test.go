@@ -0,0 +1,181 @@
+package otlpinf
+
+import (
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/netboxlabs/opentelemetry-infinity/config"
+	"github.com/netboxlabs/opentelemetry-infinity/runner"
+)
+
+func TestMissingComponents(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	reg := runner.NewRegistry(&config.Config{})
+	if err := reg.Load(logger); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	policy := config.Policy{
+		Receivers: map[string]interface{}{"otlp/in": nil, "invalid": nil},
+		Exporters: map[string]interface{}{"debug": nil},
+	}
+	got := missingComponents(policy, reg.Default())
+	want := []string{`receiver "invalid" is not available in distribution "otelcol-contrib"`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestListenPorts(t *testing.T) {
+	policy := config.Policy{
+		Receivers: map[string]interface{}{
+			"otlp": map[string]interface{}{
+				"protocols": map[string]interface{}{
+					"grpc": map[string]interface{}{"endpoint": "0.0.0.0:4317"},
+					"http": map[string]interface{}{"endpoint": "localhost:4318"},
+				},
+			},
+			"filelog": map[string]interface{}{"include": []interface{}{"/var/log/*.log"}},
+		},
+		Exporters: map[string]interface{}{
+			"otlp": map[string]interface{}{"endpoint": "collector:4317"},
+		},
+		Extensions: map[string]interface{}{
+			"health_check": map[string]interface{}{"endpoint": "${env:HOST}:13133"},
+			"pprof":        map[string]interface{}{"endpoint": "not-an-endpoint"},
+		},
+	}
+
+	if got := listenPorts(policy); !reflect.DeepEqual(got, []int{4317, 4318}) {
+		t.Errorf("expected [4317 4318], got %v", got)
+	}
+}
+
+func TestPortConflicts(t *testing.T) {
+	o := newTestOtlp()
+	withPort := func(endpoint string) config.Policy {
+		return config.Policy{Receivers: map[string]interface{}{
+			"otlp": map[string]interface{}{"protocols": map[string]interface{}{
+				"grpc": map[string]interface{}{"endpoint": endpoint},
+			}},
+		}}
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = l.Close() }()
+	bound := l.Addr().String()
+
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	freeAddr := free.Addr().String()
+	_ = free.Close()
+
+	o.policies.set("applied", RunnerInfo{Policy: withPort(freeAddr)})
+
+	if got := o.portConflicts(map[string]config.Policy{"p1": withPort("127.0.0.1:0")}); len(got) != 0 {
+		t.Errorf("expected no conflicts, got %v", got)
+	}
+	got := o.portConflicts(map[string]config.Policy{"p1": withPort(freeAddr)})
+	if len(got) != 1 || !strings.Contains(got[0], "policy 'applied'") {
+		t.Errorf("expected a conflict with the applied policy, got %v", got)
+	}
+	got = o.portConflicts(map[string]config.Policy{"p1": withPort(bound)})
+	if len(got) != 1 || !strings.Contains(got[0], "already in use") {
+		t.Errorf("expected a conflict with the bound port, got %v", got)
+	}
+	o.policies.remove("applied")
+	got = o.portConflicts(map[string]config.Policy{"p1": withPort(freeAddr), "p2": withPort(freeAddr)})
+	if len(got) != 1 || !strings.Contains(got[0], "policy 'p1'") {
+		t.Errorf("expected a conflict between the new policies, got %v", got)
+	}
+}
+
+// createPolicy with dry_run validates the policy without creating it.
+func TestCreatePolicyDryRun(t *testing.T) {
+	o := newTestOtlp()
+	o.policiesDir = t.TempDir()
+
+	body := "p1:\n  receivers:\n    otlp:\n      protocols:\n        grpc:\n          endpoint: localhost:0\n  exporters:\n    debug:\n  service:\n    pipelines:\n      metrics:\n        receivers: [otlp]\n        exporters: [debug]\n"
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", PoliciesAPI+"?dry_run=true", strings.NewReader(body))
+	req.Header.Set("Content-Type", HTTPYamlContent)
+	o.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(o.policies.names()) != 0 {
+		t.Errorf("expected no policy to be created, got %v", o.policies.names())
+	}
+	entries, err := os.ReadDir(o.policiesDir)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("expected policy files to be removed, got %v (%v)", entries, err)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", PoliciesAPI+"?dry_run=maybe", strings.NewReader(body))
+	req.Header.Set("Content-Type", HTTPYamlContent)
+	o.router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+// createPolicy with dry_run checks changes to an existing policy, whose own ports are not conflicts.
+func TestCreatePolicyDryRunExisting(t *testing.T) {
+	o := newUpdateTestOtlp(t)
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	addr := free.Addr().String()
+	_ = free.Close()
+	policy := "receivers:\n    otlp:\n      protocols:\n        grpc:\n          endpoint: " + addr +
+		"\n  exporters:\n    debug:\n  service:\n    pipelines:\n      metrics:\n        receivers: [otlp]\n        exporters: [debug]\n"
+	post := func(query string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", PoliciesAPI+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", HTTPYamlContent)
+		o.router.ServeHTTP(w, req)
+		return w
+	}
+	if w := post("", "p1:\n  "+policy); w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	// The port is bound as the collector of p1 would
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = l.Close() }()
+	files, err := os.ReadDir(o.policiesDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	changed := "p1:\n  " + strings.Replace(policy, "debug", "debug/changed", -1)
+	if w := post("?dry_run=true", changed); w.Code != http.StatusOK {
+		t.Fatalf("expected the change to be valid, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, ok := appliedPolicy(o, "p1").Exporters["debug"]; !ok {
+		t.Errorf("expected p1 to be left unchanged, got %+v", appliedPolicy(o, "p1"))
+	}
+	if entries, err := os.ReadDir(o.policiesDir); err != nil || len(entries) != len(files) {
+		t.Errorf("expected the dry run policy files to be removed, got %v (%v)", entries, err)
+	}
+	if w := post("", changed); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 without dry_run, got %d: %s", w.Code, w.Body.String())
+	}
+}
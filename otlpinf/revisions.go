@@ -0,0 +1,80 @@
+package otlpinf
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/netboxlabs/opentelemetry-infinity/config"
+)
+
+const defaultMaxRevisions = 10
+
+// Revision represents a change applied to a policy
+type Revision struct {
+	Number    int           `json:"revision" yaml:"revision"`
+	Time      time.Time     `json:"time" yaml:"time"`
+	ChangedBy string        `json:"changed_by" yaml:"changed_by"`
+	Change    string        `json:"change" yaml:"change"`
+	From      int           `json:"from_revision,omitempty" yaml:"from_revision,omitempty"`
+	Result    string        `json:"result" yaml:"result"`
+	Reason    string        `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Policy    config.Policy `json:"-" yaml:"policy"`
+}
+
+// revisionLog keeps the most recent revisions of a policy
+type revisionLog struct {
+	next      int
+	revisions []Revision
+}
+
+func (l *revisionLog) add(rev Revision, limit int) {
+	l.next++
+	rev.Number = l.next
+	l.revisions = append(l.revisions, rev)
+	if len(l.revisions) > limit {
+		l.revisions = append([]Revision(nil), l.revisions[len(l.revisions)-limit:]...)
+	}
+}
+
+func (l *revisionLog) get(number int) (Revision, bool) {
+	for _, rev := range l.revisions {
+		if rev.Number == number {
+			return rev, true
+		}
+	}
+	return Revision{}, false
+}
+
+// recordChange records a change made through the API in the revision history
+// of a policy and in the store
+func (o *OltpInf) recordChange(c *gin.Context, policy string, rev Revision, result updateResult) {
+	setAuditOutcome(c, result)
+	changedBy := requester(c)
+	o.recordRevision(policy, changedBy, rev, result)
+	o.savePolicy(policy, changedBy)
+}
+
+// recordRevision adds the change, policy and source revision of rev to the
+// history of a policy. The history is dropped together with the policy when it
+// no longer exists.
+func (o *OltpInf) recordRevision(policy string, changedBy string, rev Revision, result updateResult) {
+	limit := o.conf.MaxRevisions
+	if limit <= 0 {
+		limit = defaultMaxRevisions
+	}
+	rev.Time = time.Now()
+	rev.ChangedBy = changedBy
+	rev.Result = result.Result
+	rev.Reason = result.Reason
+	o.policies.addRevision(policy, rev, limit)
+}
+
+// requester identifies the client a request was made by, using its
+// authenticated principal when authentication is enabled
+func requester(c *gin.Context) string {
+	if p, ok := principal(c); ok {
+		return p.Name
+	}
+	return c.ClientIP()
+}
@@ -0,0 +1,24 @@
+package otlpinf
+
+import "testing"
+
+// revisionLog numbers revisions sequentially and only keeps the most recent ones.
+func TestRevisionLog(t *testing.T) {
+	var l revisionLog
+	for i := 0; i < 5; i++ {
+		l.add(Revision{Change: "updated"}, 3)
+	}
+
+	if len(l.revisions) != 3 {
+		t.Fatalf("expected 3 revisions, got %d", len(l.revisions))
+	}
+	if first, last := l.revisions[0].Number, l.revisions[2].Number; first != 3 || last != 5 {
+		t.Errorf("expected revisions 3 to 5, got %d to %d", first, last)
+	}
+	if _, ok := l.get(2); ok {
+		t.Errorf("expected revision 2 to be dropped")
+	}
+	if rev, ok := l.get(4); !ok || rev.Number != 4 {
+		t.Errorf("expected revision 4, got %+v", rev)
+	}
+}
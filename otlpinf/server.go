@@ -1,24 +1,35 @@
 package otlpinf
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"sort"
+	"strconv"
 	"time"
 
 	yson "github.com/ghodss/yaml"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/yaml.v3"
 
+	"github.com/netboxlabs/opentelemetry-infinity/auth"
 	"github.com/netboxlabs/opentelemetry-infinity/config"
 	"github.com/netboxlabs/opentelemetry-infinity/runner"
 )
 
+const bodyPoliciesKey = "body_policies"
+
 type returnPolicyData struct {
-	State runner.State `yaml:"status"`
+	State     runner.State `yaml:"status"`
+	ManagedBy string       `yaml:"managed_by,omitempty"`
 	config.Policy
 }
 
@@ -26,32 +37,85 @@ type returnValue struct {
 	Message string `json:"message"`
 }
 
+type validationFailure struct {
+	Message string   `json:"message"`
+	Policy  string   `json:"policy,omitempty"`
+	Errors  []string `json:"errors"`
+}
+
+type updateResult struct {
+	Message string `json:"message"`
+	Result  string `json:"result"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+type dryRunResult struct {
+	Message  string   `json:"message"`
+	Policies []string `json:"policies"`
+}
+
 func (o *OltpInf) setupRouter() {
 	gin.SetMode(gin.ReleaseMode)
 	o.router = gin.New()
+	o.router.Use(o.metrics.instrument, traceRequests())
+	o.router.GET("/healthz", o.getHealth)
+	o.router.GET("/readyz", o.getReadiness)
 
 	// Routes
-	o.router.GET("/api/v1/status", o.getStatus)
-	o.router.GET("/api/v1/capabilities", o.getCapabilities)
-	o.router.GET("/api/v1/policies", o.getPolicies)
-	o.router.POST("/api/v1/policies", o.createPolicy)
-	o.router.GET("/api/v1/policies/:policy", o.getPolicy)
-	o.router.DELETE("/api/v1/policies/:policy", o.deletePolicy)
+	api := o.router.Group("/api/v1", o.authenticate)
+	read := o.authorize(auth.ActionRead)
+	operate := o.authorize(auth.ActionOperate)
+	write := o.authorize(auth.ActionWrite)
+	api.GET("/status", read, o.getStatus)
+	api.GET("/capabilities", read, o.getCapabilities)
+	api.GET("/policies", read, o.getPolicies)
+	api.POST("/policies", o.audited("create"), write, o.createPolicy)
+	api.GET("/policies/:policy", read, o.getPolicy)
+	api.PUT("/policies/:policy", o.audited("update"), write, o.updatePolicy)
+	api.PATCH("/policies/:policy", o.audited("patch"), write, o.patchPolicy)
+	api.DELETE("/policies/:policy", o.audited("delete"), write, o.deletePolicy)
+	api.POST("/policies/:policy/reset", o.audited("reset"), operate, o.resetPolicy)
+	api.POST("/policies/:policy/rollback", o.audited("rollback"), write, o.rollbackPolicy)
+	api.GET("/policies/:policy/revisions", read, o.getPolicyRevisions)
+	api.GET("/policies/:policy/revisions/:revision", read, o.getPolicyRevision)
+	api.GET("/policies/:policy/events", read, o.getPolicyEvents)
+	api.GET("/policies/:policy/logs", read, o.getPolicyLogs)
+	api.GET("/policies/:policy/logs/stream", read, o.streamPolicyLogs)
+	api.GET("/audit", o.authorizeUnscoped(auth.ActionWrite), o.getAudit)
+	o.router.GET("/metrics", o.authenticate, o.authorizeUnscoped(auth.ActionRead), o.metrics.handler())
 }
 
 func (o *OltpInf) startServer() <-chan error {
+	tlsConfig, err := serverTLSConfig(o.logger, o.conf)
+	if err != nil {
+		return o.startFailure(err)
+	}
 	o.setupRouter()
 	serverAddr := fmt.Sprintf("%s:%d", o.conf.ServerHost, o.conf.ServerPort)
 	errCh := make(chan error, 1)
 
-	o.httpServer = &http.Server{
-		Addr:    serverAddr,
-		Handler: o.router,
+	// Request contexts are cancelled on shutdown so that log streams end
+	// instead of holding the server open
+	baseCtx, cancelRequests := context.WithCancel(o.ctx)
+	srv := &http.Server{
+		Addr:        serverAddr,
+		Handler:     o.router,
+		TLSConfig:   tlsConfig,
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
 	}
+	srv.RegisterOnShutdown(cancelRequests)
+	o.httpServer = srv
 
 	go func() {
-		o.logger.Info("starting otlp_inf server", "address", serverAddr)
-		if err := o.httpServer.ListenAndServe(); err != nil {
+		var err error
+		if tlsConfig != nil {
+			o.logger.Info("starting otlp_inf server", "address", serverAddr, "tls", true, "mtls", o.conf.TLSClientCAFile != "")
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			o.logger.Info("starting otlp_inf server", "address", serverAddr)
+			err = srv.ListenAndServe()
+		}
+		if err != nil {
 			if errors.Is(err, http.ErrServerClosed) {
 				close(errCh)
 				return
@@ -65,12 +129,18 @@ func (o *OltpInf) startServer() <-chan error {
 }
 
 func (o *OltpInf) getStatus(c *gin.Context) {
-	o.stat.UpTime = time.Since(o.stat.StartTime)
-	c.IndentedJSON(http.StatusOK, o.stat)
+	stat := o.stat
+	stat.UpTime = time.Since(stat.StartTime)
+	c.IndentedJSON(http.StatusOK, stat)
 }
 
 func (o *OltpInf) getCapabilities(c *gin.Context) {
-	j, err := yson.YAMLToJSON(o.capabilities)
+	d, err := o.distributions.Get(c.Query("distribution"))
+	if err != nil {
+		c.IndentedJSON(http.StatusNotFound, returnValue{err.Error()})
+		return
+	}
+	j, err := yson.YAMLToJSON(d.Capabilities)
 	if err != nil {
 		c.IndentedJSON(http.StatusBadRequest, returnValue{err.Error()})
 		return
@@ -85,90 +155,571 @@ func (o *OltpInf) getCapabilities(c *gin.Context) {
 }
 
 func (o *OltpInf) getPolicies(c *gin.Context) {
-	policies := make([]string, 0, len(o.policies))
-	for k := range o.policies {
-		policies = append(policies, k)
+	policies := []string{}
+	for _, k := range o.policies.names() {
+		if o.visible(c, k) {
+			policies = append(policies, k)
+		}
 	}
 	c.IndentedJSON(http.StatusOK, policies)
 }
 
 func (o *OltpInf) getPolicy(c *gin.Context) {
 	policy := c.Param("policy")
-	rInfo, ok := o.policies[policy]
+	rInfo, ok := o.policies.get(policy)
 	if ok {
-		c.YAML(http.StatusOK, map[string]returnPolicyData{policy: {rInfo.Instance.GetStatus(), rInfo.Policy}})
+		managedBy, _ := o.policies.managedBy(policy)
+		c.YAML(http.StatusOK, map[string]returnPolicyData{policy: {State: rInfo.Instance.GetStatus(), ManagedBy: managedBy, Policy: rInfo.Policy}})
 	} else {
 		c.IndentedJSON(http.StatusNotFound, returnValue{"policy not found"})
 	}
 }
 
 func (o *OltpInf) createPolicy(c *gin.Context) {
-	if t := c.Request.Header.Get("Content-type"); t != "application/x-yaml" {
-		c.IndentedJSON(http.StatusBadRequest, returnValue{"invalid Content-Type. Only 'application/x-yaml' is supported"})
+	payload, ok := parsePolicies(c)
+	if !ok {
 		return
 	}
-	body, err := io.ReadAll(c.Request.Body)
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
 	if err != nil {
-		c.IndentedJSON(http.StatusBadRequest, returnValue{err.Error()})
+		c.IndentedJSON(http.StatusBadRequest, returnValue{"invalid 'dry_run' parameter, expected a boolean"})
 		return
 	}
-	var payload map[string]config.Policy
-	if err = yaml.Unmarshal(body, &payload); err != nil {
-		c.IndentedJSON(http.StatusBadRequest, returnValue{err.Error()})
-		return
+	names := make([]string, 0, len(payload))
+	for policy := range payload {
+		names = append(names, policy)
 	}
-
+	unlock := o.policies.lock(names...)
+	defer unlock()
+	// Dry runs check changes to existing policies too, whose own ports are not
+	// reported as conflicts
 	for policy := range payload {
-		_, ok := o.policies[policy]
-		if ok {
+		if o.policies.exists(policy) && !dryRun {
 			c.IndentedJSON(http.StatusConflict, returnValue{"policy '" + policy + "' already exists"})
 			return
+		}
+	}
 
+	runners, pErr := o.preparePolicies(c.Request.Context(), payload)
+	if pErr != nil {
+		c.IndentedJSON(pErr.code, pErr.body)
+		return
+	}
+
+	if dryRun {
+		for _, r := range runners {
+			o.discardRunner(r)
 		}
+		sort.Strings(names)
+		c.IndentedJSON(http.StatusOK, dryRunResult{"policies are valid", names})
+		return
 	}
 
 	var newPolicies []string
 	newPolicyData := make(map[string]returnPolicyData)
 	for policy, data := range payload {
-		r := runner.NewRunner(o.logger, policy, o.policiesDir, o.conf)
-		if err := r.Configure(&data); err != nil {
+		r := runners[policy]
+		// Runners are registered before they start so that their starting
+		// status can be read
+		o.policies.set(policy, RunnerInfo{Policy: data, Instance: r})
+		newPolicies = append(newPolicies, policy)
+		if err := o.startRunner(c.Request.Context(), policy, r); err != nil {
 			for _, p := range newPolicies {
-				r, ok := o.policies[p]
-				if ok {
-					r.Instance.Stop(o.ctx)
-					delete(o.policies, policy)
+				if r, ok := o.policies.remove(p); ok {
+					o.stopRunner(r.Instance)
 				}
+				delete(runners, p)
 			}
-			c.IndentedJSON(http.StatusBadRequest, returnValue{err.Error()})
-			return
-		}
-		runnerCtx := context.WithValue(o.ctx, routineKey, policy)
-		if err := r.Start(context.WithCancel(runnerCtx)); err != nil {
-			for _, p := range newPolicies {
-				r, ok := o.policies[p]
-				if ok {
-					r.Instance.Stop(o.ctx)
-					delete(o.policies, policy)
-				}
+			for _, r := range runners {
+				o.discardRunner(r)
 			}
 			c.IndentedJSON(http.StatusBadRequest, returnValue{err.Error()})
 			return
 		}
-		o.policies[policy] = RunnerInfo{Policy: data, Instance: r}
-		newPolicies = append(newPolicies, policy)
-		newPolicyData[policy] = returnPolicyData{r.GetStatus(), data}
+		newPolicyData[policy] = returnPolicyData{State: r.GetStatus(), Policy: data}
+	}
+	for policy, data := range payload {
+		o.recordChange(c, policy, Revision{Change: "created", Policy: data}, updateResult{Result: "applied"})
 	}
 	c.YAML(http.StatusCreated, newPolicyData)
 }
 
+// parsePolicies reads a YAML request body holding policies by name. On
+// failure the error response has been written and false is returned.
+func parsePolicies(c *gin.Context) (map[string]config.Policy, bool) {
+	if t := c.Request.Header.Get("Content-type"); t != "application/x-yaml" {
+		c.IndentedJSON(http.StatusBadRequest, returnValue{"invalid Content-Type. Only 'application/x-yaml' is supported"})
+		return nil, false
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, returnValue{err.Error()})
+		return nil, false
+	}
+	var payload map[string]config.Policy
+	if err = yaml.Unmarshal(body, &payload); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, returnValue{err.Error()})
+		return nil, false
+	}
+	return payload, true
+}
+
+// bodyPolicies returns the policies of a YAML request body of a POST or PUT
+// request, if it holds any, for middlewares. The body is left for the handler
+// to read and validate.
+func bodyPolicies(c *gin.Context) map[string]config.Policy {
+	if v, ok := c.Get(bodyPoliciesKey); ok {
+		return v.(map[string]config.Policy)
+	}
+	var payload map[string]config.Policy
+	if c.Request.Method == http.MethodPost || c.Request.Method == http.MethodPut {
+		body, err := io.ReadAll(c.Request.Body)
+		if err == nil {
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			if yaml.Unmarshal(body, &payload) != nil {
+				payload = nil
+			}
+		}
+	}
+	c.Set(bodyPoliciesKey, payload)
+	return payload
+}
+
+// preparePolicies runs every pre-flight check on the policies and returns
+// configured, validated runners that have not been started yet
+func (o *OltpInf) preparePolicies(ctx context.Context, payload map[string]config.Policy) (runners map[string]*runner.Runner, pErr *preflightError) {
+	ctx, span := tracer.Start(ctx, "preparePolicies", trace.WithAttributes(attribute.Int("policies", len(payload))))
+	defer func() {
+		if pErr != nil {
+			setSpanError(span, pErr)
+		}
+		span.End()
+	}()
+	distributions := make(map[string]*runner.Distribution, len(payload))
+	for policy, data := range payload {
+		d, err := o.resolveDistribution(data.Otlpinf)
+		if err != nil {
+			return nil, &preflightError{http.StatusBadRequest, returnValue{"policy '" + policy + "': " + err.Error()}}
+		}
+		if missing := missingComponents(data, d); len(missing) > 0 {
+			return nil, &preflightError{http.StatusBadRequest, validationFailure{"policy '" + policy + "' is invalid", policy, missing}}
+		}
+		distributions[policy] = d
+	}
+	if conflicts := o.portConflicts(payload); len(conflicts) > 0 {
+		return nil, &preflightError{http.StatusConflict, validationFailure{"port conflict", "", conflicts}}
+	}
+
+	runners = make(map[string]*runner.Runner, len(payload))
+	discardAll := func() {
+		for _, r := range runners {
+			o.discardRunner(r)
+		}
+	}
+	for policy, data := range payload {
+		r := runner.NewRunner(o.logger, policy, o.policiesDir, o.conf)
+		r.SetCollector(distributions[policy].Collector)
+		r.OnLaunch(o.metrics.observeLaunch)
+		if err := configure(ctx, policy, r, &data); err != nil {
+			discardAll()
+			return nil, &preflightError{http.StatusBadRequest, returnValue{err.Error()}}
+		}
+		runners[policy] = r
+		if err := validate(ctx, policy, r); err != nil {
+			discardAll()
+			var vErr *runner.ValidationError
+			if errors.As(err, &vErr) {
+				return nil, &preflightError{http.StatusBadRequest, validationFailure{"policy '" + policy + "' is invalid", policy, vErr.Details}}
+			}
+			return nil, &preflightError{http.StatusBadRequest, returnValue{err.Error()}}
+		}
+	}
+	return runners, nil
+}
+
+// startRunner starts a runner in a span of ctx. The runner records its state
+// transitions while starting as events of the span. The context of a runner
+// that fails to start is cancelled.
+func (o *OltpInf) startRunner(ctx context.Context, policy string, r *runner.Runner) error {
+	_, span := tracer.Start(ctx, "Runner.Start", trace.WithAttributes(attribute.String("policy", policy)))
+	defer span.End()
+	runnerCtx, cancel := context.WithCancel(trace.ContextWithSpan(context.WithValue(o.ctx, routineKey, policy), span))
+	err := r.Start(runnerCtx, cancel)
+	if err != nil {
+		cancel()
+	}
+	setSpanError(span, err)
+	return err
+}
+
+func (o *OltpInf) discardRunner(r *runner.Runner) {
+	if err := r.Discard(); err != nil {
+		o.logger.Warn("failed to remove policy file", "error", err)
+	}
+}
+
+// stopRunner stops a runner that is no longer applied and removes its policy
+// file
+func (o *OltpInf) stopRunner(r *runner.Runner) {
+	r.Stop(o.ctx)
+	o.discardRunner(r)
+}
+
+func (o *OltpInf) updatePolicy(c *gin.Context) {
+	policy := c.Param("policy")
+	unlock := o.policies.lock(policy)
+	defer unlock()
+	current, ok := o.policies.get(policy)
+	if !ok {
+		c.IndentedJSON(http.StatusNotFound, returnValue{"policy not found"})
+		return
+	}
+	if o.rejectManaged(c, policy) {
+		return
+	}
+	payload, ok := parsePolicies(c)
+	if !ok {
+		return
+	}
+	data, ok := payload[policy]
+	if !ok || len(payload) != 1 {
+		c.IndentedJSON(http.StatusBadRequest, returnValue{"request must contain only policy '" + policy + "'"})
+		return
+	}
+
+	runners, pErr := o.preparePolicies(c.Request.Context(), payload)
+	if pErr != nil {
+		c.IndentedJSON(pErr.code, pErr.body)
+		return
+	}
+
+	code, result := o.replacePolicy(c.Request.Context(), policy, current, RunnerInfo{Policy: data, Instance: runners[policy]})
+	o.recordChange(c, policy, Revision{Change: "updated", Policy: data}, result)
+	c.IndentedJSON(code, result)
+}
+
+func (o *OltpInf) patchPolicy(c *gin.Context) {
+	policy := c.Param("policy")
+	unlock := o.policies.lock(policy)
+	defer unlock()
+	current, ok := o.policies.get(policy)
+	if !ok {
+		c.IndentedJSON(http.StatusNotFound, returnValue{"policy not found"})
+		return
+	}
+	if o.rejectManaged(c, policy) {
+		return
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, returnValue{err.Error()})
+		return
+	}
+	data, err := applyPolicyPatch(current.Policy, c.ContentType(), body)
+	if errors.Is(err, errPatchContentType) {
+		c.IndentedJSON(http.StatusUnsupportedMediaType, returnValue{err.Error()})
+		return
+	}
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, returnValue{"policy '" + policy + "' could not be patched: " + err.Error()})
+		return
+	}
+	if !o.authorizeResult(c, policy, data) {
+		return
+	}
+
+	runners, pErr := o.preparePolicies(c.Request.Context(), map[string]config.Policy{policy: data})
+	if pErr != nil {
+		c.IndentedJSON(pErr.code, pErr.body)
+		return
+	}
+
+	code, result := o.replacePolicy(c.Request.Context(), policy, current, RunnerInfo{Policy: data, Instance: runners[policy]})
+	o.recordChange(c, policy, Revision{Change: "patched", Policy: data}, result)
+	if code != http.StatusOK {
+		c.IndentedJSON(code, result)
+		return
+	}
+	info, _ := o.policies.get(policy)
+	c.YAML(http.StatusOK, map[string]returnPolicyData{policy: {State: info.Instance.GetStatus(), Policy: data}})
+}
+
+// replacePolicy stops the current runner of a policy and starts the next one,
+// which is registered while it starts. If the next runner fails to start, the
+// current policy is started again. If that fails too, the current policy is
+// kept in the failed state, so that it can be reset and is restored on the
+// next start.
+func (o *OltpInf) replacePolicy(ctx context.Context, policy string, current RunnerInfo, next RunnerInfo) (int, updateResult) {
+	o.stopRunner(current.Instance)
+	next.Instance.Adopt(current.Instance)
+	o.policies.set(policy, next)
+	err := o.startRunner(ctx, policy, next.Instance)
+	if err == nil {
+		o.logger.Info("policy updated", "policy", policy)
+		return http.StatusOK, updateResult{policy + " was updated", "applied", ""}
+	}
+
+	o.discardRunner(next.Instance)
+	o.logger.Warn("policy update failed, rolling back", "policy", policy, "error", err)
+	if _, rbErr := o.restartPolicy(ctx, policy, current.Policy, next.Instance); rbErr != nil {
+		info, _ := o.policies.get(policy)
+		o.policies.set(policy, RunnerInfo{Policy: current.Policy, Instance: info.Instance})
+		o.logger.Error("policy rollback failed, policy kept as failed", "policy", policy, "error", rbErr)
+		return http.StatusInternalServerError, updateResult{policy + " could not be rolled back and has failed", "failed", err.Error() + "; rollback: " + rbErr.Error()}
+	}
+	return http.StatusBadRequest, updateResult{policy + " was rolled back", "rolled_back", err.Error()}
+}
+
+// restartPolicy registers and starts a new runner for a policy, taking over
+// the history of the given runner
+func (o *OltpInf) restartPolicy(ctx context.Context, policy string, data config.Policy, from *runner.Runner) (*runner.Runner, error) {
+	r, err := o.configureRunner(policy, data)
+	if err != nil {
+		return nil, err
+	}
+	r.Adopt(from)
+	o.policies.set(policy, RunnerInfo{Policy: data, Instance: r})
+	if err = o.startRunner(ctx, policy, r); err != nil {
+		o.discardRunner(r)
+		return nil, err
+	}
+	return r, nil
+}
+
+// configureRunner creates a runner for a policy that has already passed the
+// pre-flight checks
+func (o *OltpInf) configureRunner(policy string, data config.Policy) (*runner.Runner, error) {
+	d, err := o.resolveDistribution(data.Otlpinf)
+	if err != nil {
+		return nil, err
+	}
+	r := runner.NewRunner(o.logger, policy, o.policiesDir, o.conf)
+	r.SetCollector(d.Collector)
+	r.OnLaunch(o.metrics.observeLaunch)
+	if err = r.Configure(&data); err != nil {
+		o.discardRunner(r)
+		return nil, err
+	}
+	return r, nil
+}
+
 func (o *OltpInf) deletePolicy(c *gin.Context) {
 	policy := c.Param("policy")
-	r, ok := o.policies[policy]
-	if ok {
-		r.Instance.Stop(o.ctx)
-		delete(o.policies, policy)
+	unlock := o.policies.lock(policy)
+	defer unlock()
+	if o.policies.exists(policy) {
+		if o.rejectManaged(c, policy) {
+			return
+		}
+		r, _ := o.policies.remove(policy)
+		o.stopRunner(r.Instance)
+		o.savePolicy(policy, requester(c))
 		c.IndentedJSON(http.StatusOK, returnValue{policy + " was deleted"})
 	} else {
 		c.IndentedJSON(http.StatusNotFound, returnValue{"policy not found"})
 	}
 }
+
+func (o *OltpInf) resetPolicy(c *gin.Context) {
+	policy := c.Param("policy")
+	unlock := o.policies.lock(policy)
+	defer unlock()
+	r, ok := o.policies.get(policy)
+	if !ok {
+		c.IndentedJSON(http.StatusNotFound, returnValue{"policy not found"})
+		return
+	}
+	// Failed policies have no collector to relaunch and are started again
+	if r.Instance.GetStatus().StatusText == "failed" {
+		if _, err := o.restartPolicy(c.Request.Context(), policy, r.Policy, r.Instance); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, returnValue{err.Error()})
+			return
+		}
+		c.IndentedJSON(http.StatusOK, returnValue{policy + " was reset"})
+		return
+	}
+	if err := r.Instance.Reset(); err != nil {
+		c.IndentedJSON(http.StatusConflict, returnValue{err.Error()})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, returnValue{policy + " was reset"})
+}
+
+func (o *OltpInf) getPolicyRevisions(c *gin.Context) {
+	policy := c.Param("policy")
+	if !o.policies.exists(policy) {
+		c.IndentedJSON(http.StatusNotFound, returnValue{"policy not found"})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, o.policies.revisionHistory(policy))
+}
+
+func (o *OltpInf) getPolicyEvents(c *gin.Context) {
+	policy := c.Param("policy")
+	r, ok := o.policies.get(policy)
+	if !ok {
+		c.IndentedJSON(http.StatusNotFound, returnValue{"policy not found"})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, r.Instance.Transitions())
+}
+
+func (o *OltpInf) getPolicyRevision(c *gin.Context) {
+	rev, ok := o.findRevision(c, c.Param("revision"))
+	if !ok {
+		return
+	}
+	c.YAML(http.StatusOK, rev)
+}
+
+func (o *OltpInf) rollbackPolicy(c *gin.Context) {
+	policy := c.Param("policy")
+	unlock := o.policies.lock(policy)
+	defer unlock()
+	current, ok := o.policies.get(policy)
+	if !ok {
+		c.IndentedJSON(http.StatusNotFound, returnValue{"policy not found"})
+		return
+	}
+	if o.rejectManaged(c, policy) {
+		return
+	}
+	rev, ok := o.findRevision(c, c.Query("revision"))
+	if !ok {
+		return
+	}
+	// Revisions that were not applied hold the policy that was rejected
+	if rev.Result != "applied" {
+		c.IndentedJSON(http.StatusConflict, returnValue{"revision " + strconv.Itoa(rev.Number) + " was not applied (" + rev.Result + ") and cannot be rolled back to"})
+		return
+	}
+	if !o.authorizeResult(c, policy, rev.Policy) {
+		return
+	}
+
+	runners, pErr := o.preparePolicies(c.Request.Context(), map[string]config.Policy{policy: rev.Policy})
+	if pErr != nil {
+		c.IndentedJSON(pErr.code, pErr.body)
+		return
+	}
+
+	code, result := o.replacePolicy(c.Request.Context(), policy, current, RunnerInfo{Policy: rev.Policy, Instance: runners[policy]})
+	o.recordChange(c, policy, Revision{Change: "rollback", From: rev.Number, Policy: rev.Policy}, result)
+	c.IndentedJSON(code, result)
+}
+
+// findRevision looks up a revision of the requested policy by number. On
+// failure the error response has been written and false is returned.
+func (o *OltpInf) findRevision(c *gin.Context, number string) (Revision, bool) {
+	policy := c.Param("policy")
+	if !o.policies.exists(policy) {
+		c.IndentedJSON(http.StatusNotFound, returnValue{"policy not found"})
+		return Revision{}, false
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil || n <= 0 {
+		c.IndentedJSON(http.StatusBadRequest, returnValue{"invalid revision, expected a positive integer"})
+		return Revision{}, false
+	}
+	if rev, ok := o.policies.revision(policy, n); ok {
+		return rev, true
+	}
+	c.IndentedJSON(http.StatusNotFound, returnValue{"revision not found"})
+	return Revision{}, false
+}
+
+func (o *OltpInf) getPolicyLogs(c *gin.Context) {
+	policy := c.Param("policy")
+	r, ok := o.policies.get(policy)
+	if !ok {
+		c.IndentedJSON(http.StatusNotFound, returnValue{"policy not found"})
+		return
+	}
+	filter, err := parseLogFilter(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, returnValue{err.Error()})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, r.Instance.Logs(filter))
+}
+
+func parseLogFilter(c *gin.Context) (runner.LogFilter, error) {
+	var filter runner.LogFilter
+	if since := c.Query("since"); since != "" {
+		ts, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return filter, errors.New("invalid 'since' parameter, expected RFC3339 timestamp")
+		}
+		filter.Since = ts
+	}
+	level, err := parseLogLevel(c)
+	if err != nil {
+		return filter, err
+	}
+	filter.Level = level
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return filter, errors.New("invalid 'limit' parameter, expected a positive integer")
+		}
+		filter.Limit = n
+	}
+	return filter, nil
+}
+
+func parseLogLevel(c *gin.Context) (slog.Level, error) {
+	level := slog.LevelDebug
+	if l := c.Query("level"); l != "" {
+		if err := level.UnmarshalText([]byte(l)); err != nil {
+			return level, errors.New("invalid 'level' parameter, expected one of debug, info, warn or error")
+		}
+	}
+	return level, nil
+}
+
+func (o *OltpInf) streamPolicyLogs(c *gin.Context) {
+	policy := c.Param("policy")
+	r, ok := o.policies.get(policy)
+	if !ok {
+		c.IndentedJSON(http.StatusNotFound, returnValue{"policy not found"})
+		return
+	}
+	level, err := parseLogLevel(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, returnValue{err.Error()})
+		return
+	}
+
+	records, unsubscribe := r.Instance.SubscribeLogs(level)
+	defer unsubscribe()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("Content-Type", "text/event-stream")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	c.Stream(func(_ io.Writer) bool {
+		select {
+		case rec, ok := <-records:
+			if !ok {
+				return false
+			}
+			c.SSEvent("log", rec)
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+func (o *OltpInf) resolveDistribution(opts config.PolicyOptions) (*runner.Distribution, error) {
+	d, err := o.distributions.Get(opts.Distribution)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Version != "" && opts.Version != d.Version {
+		return nil, fmt.Errorf("distribution %q runs version %q, not %q", d.Name, d.Version, opts.Version)
+	}
+	return d, nil
+}
@@ -0,0 +1,101 @@
+package otlpinf
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	otelprom "go.opentelemetry.io/contrib/bridges/prometheus"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/netboxlabs/opentelemetry-infinity/config"
+	"github.com/netboxlabs/opentelemetry-infinity/runner"
+)
+
+const serviceName = "otlpinf"
+
+var tracer = otel.Tracer("github.com/netboxlabs/opentelemetry-infinity/otlpinf")
+
+// startTelemetry exports the traces of otlpinf and the metrics served on
+// /metrics over OTLP/HTTP when an endpoint is configured. The standard
+// OTEL_EXPORTER_OTLP_* variables, such as for headers, also apply.
+func (o *OltpInf) startTelemetry(ctx context.Context) error {
+	if o.conf.OTLPEndpoint == "" {
+		return nil
+	}
+	endpoint := strings.TrimSuffix(o.conf.OTLPEndpoint, "/")
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return err
+	}
+	traceExporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint+"/v1/traces"))
+	if err != nil {
+		return err
+	}
+	metricExporter, err := otlpmetrichttp.New(ctx, otlpmetrichttp.WithEndpointURL(endpoint+"/v1/metrics"))
+	if err != nil {
+		return err
+	}
+
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(traceExporter), sdktrace.WithResource(res))
+	producer := otelprom.NewMetricProducer(otelprom.WithGatherer(o.metrics.registry))
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithResource(res),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter, sdkmetric.WithProducer(producer))))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	o.stopTelemetry = func(ctx context.Context) error {
+		return errors.Join(tp.Shutdown(ctx), mp.Shutdown(ctx))
+	}
+	o.logger.Info("exporting telemetry over OTLP", "endpoint", endpoint)
+	return nil
+}
+
+// traceRequests starts a span for every REST request but metric scrapes and
+// probes
+func traceRequests() gin.HandlerFunc {
+	return otelgin.Middleware(serviceName, otelgin.WithGinFilter(func(c *gin.Context) bool {
+		switch c.FullPath() {
+		case "/metrics", "/healthz", "/readyz":
+			return false
+		}
+		return true
+	}))
+}
+
+// configure configures a runner in a span of ctx
+func configure(ctx context.Context, policy string, r *runner.Runner, data *config.Policy) error {
+	_, span := tracer.Start(ctx, "Runner.Configure", trace.WithAttributes(attribute.String("policy", policy)))
+	defer span.End()
+	err := r.Configure(data)
+	setSpanError(span, err)
+	return err
+}
+
+// validate validates the policy of a runner with its collector in a span of ctx
+func validate(ctx context.Context, policy string, r *runner.Runner) error {
+	ctx, span := tracer.Start(ctx, "Runner.Validate", trace.WithAttributes(attribute.String("policy", policy)))
+	defer span.End()
+	err := r.Validate(ctx)
+	setSpanError(span, err)
+	return err
+}
+
+// setSpanError marks a span as failed, unless err is nil
+func setSpanError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,166 @@
+package otlpinf
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpStandIn is an OTLP/HTTP endpoint keeping the spans and metric names it receives
+type otlpStandIn struct {
+	mu      sync.Mutex
+	spans   map[string]*tracepb.Span
+	metrics map[string]bool
+}
+
+func (s *otlpStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var resp proto.Message
+	switch r.URL.Path {
+	case "/v1/traces":
+		var req coltracepb.ExportTraceServiceRequest
+		if err = proto.Unmarshal(body, &req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, span := range ss.Spans {
+					s.spans[span.Name] = span
+				}
+			}
+		}
+		resp = &coltracepb.ExportTraceServiceResponse{}
+	case "/v1/metrics":
+		var req colmetricpb.ExportMetricsServiceRequest
+		if err = proto.Unmarshal(body, &req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, rm := range req.ResourceMetrics {
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					s.metrics[m.Name] = true
+				}
+			}
+		}
+		resp = &colmetricpb.ExportMetricsServiceResponse{}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	b, _ := proto.Marshal(resp)
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(b)
+}
+
+// Requests, pre-flight checks and runner starts and stops are traced and exported over OTLP with the metrics.
+func TestTelemetry(t *testing.T) {
+	standIn := &otlpStandIn{spans: make(map[string]*tracepb.Span), metrics: make(map[string]bool)}
+	srv := httptest.NewServer(standIn)
+	defer srv.Close()
+
+	// startTelemetry sets the global provider and propagator, which other tests use
+	tp, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(tp)
+		otel.SetTextMapPropagator(propagator)
+	})
+
+	o := newUpdateTestOtlp(t)
+	o.conf.OTLPEndpoint = srv.URL
+	if err := o.startTelemetry(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	o.setupRouter()
+
+	policy := "receivers:\n    otlp:\n  exporters:\n    debug:\n  service:\n    pipelines:\n      metrics:\n        receivers: [otlp]\n        exporters: [debug]\n"
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", PoliciesAPI, strings.NewReader("p1:\n  "+policy))
+	req.Header.Set("Content-Type", HTTPYamlContent)
+	o.router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", PoliciesAPI+"/p1", nil)
+	o.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if err := o.stopTelemetry(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	standIn.mu.Lock()
+	defer standIn.mu.Unlock()
+	var request *tracepb.Span
+	for name, span := range standIn.spans {
+		if strings.HasSuffix(name, "/api/v1/policies") {
+			request = span
+		}
+	}
+	if request == nil {
+		t.Fatalf("expected a span for the request, got %v", standIn.spans)
+	}
+	for _, name := range []string{"preparePolicies", "Runner.Configure", "Runner.Validate", "Runner.Start"} {
+		span, ok := standIn.spans[name]
+		if !ok {
+			t.Errorf("expected a %s span", name)
+			continue
+		}
+		if string(span.TraceId) != string(request.TraceId) {
+			t.Errorf("expected %s in the trace of the request", name)
+		}
+	}
+	start, ok := standIn.spans["Runner.Start"]
+	if ok {
+		if transitions := spanTransitions(start); strings.Join(transitions, ",") != "starting,running" {
+			t.Errorf("expected the starting and running transitions, got %v", transitions)
+		}
+	}
+	if stop, ok := standIn.spans["Runner.Stop"]; !ok {
+		t.Errorf("expected a Runner.Stop span")
+	} else {
+		if transitions := spanTransitions(stop); strings.Join(transitions, ",") != "stopping,stopped" {
+			t.Errorf("expected the stopping and stopped transitions, got %v", transitions)
+		}
+		if start != nil && (len(stop.Links) != 1 || string(stop.Links[0].SpanId) != string(start.SpanId)) {
+			t.Errorf("expected Runner.Stop to be linked to Runner.Start, got %v", stop.Links)
+		}
+	}
+	if !standIn.metrics["otlpinf_runner_start_duration_seconds"] {
+		t.Errorf("expected the otlpinf metrics to be exported, got %v", standIn.metrics)
+	}
+}
+
+// spanTransitions returns the statuses of the state_transition events of a span
+func spanTransitions(span *tracepb.Span) []string {
+	var transitions []string
+	for _, e := range span.Events {
+		if e.Name == "state_transition" {
+			for _, a := range e.Attributes {
+				if a.Key == "to" {
+					transitions = append(transitions, a.Value.GetStringValue())
+				}
+			}
+		}
+	}
+	return transitions
+}
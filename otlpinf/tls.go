@@ -0,0 +1,166 @@
+package otlpinf
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/netboxlabs/opentelemetry-infinity/config"
+)
+
+const certCheckInterval = time.Second
+
+var tlsVersions = map[string]uint16{
+	"":    tls.VersionTLS12,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// certReloader serves the server certificate and the client CAs from files
+// and reloads them when the files change on disk. A file that fails to load
+// is logged and the previously loaded one is kept.
+type certReloader struct {
+	logger   *slog.Logger
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mu        sync.Mutex
+	checked   time.Time
+	stamps    map[string]fileStamp
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+func newCertReloader(logger *slog.Logger, certFile string, keyFile string, caFile string) (*certReloader, error) {
+	r := &certReloader{logger: logger, certFile: certFile, keyFile: keyFile, caFile: caFile}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) files() []string {
+	files := []string{r.certFile, r.keyFile}
+	if r.caFile != "" {
+		files = append(files, r.caFile)
+	}
+	return files
+}
+
+// load reads the certificate, key and client CA files
+func (r *certReloader) load() error {
+	stamps := make(map[string]fileStamp)
+	for _, f := range r.files() {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return err
+		}
+		stamps[f] = fileStamp{fi.ModTime(), fi.Size()}
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("%s: no certificates found", r.caFile)
+		}
+	}
+	r.stamps = stamps
+	r.cert = &cert
+	r.clientCAs = pool
+	return nil
+}
+
+// current returns the certificate and client CAs, reloading them first if
+// their files changed since they were last checked
+func (r *certReloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.checked) >= certCheckInterval {
+		r.checked = time.Now()
+		if r.changed() {
+			if err := r.load(); err != nil {
+				r.logger.Error("failed to reload TLS certificates, keeping the previous ones", "error", err)
+			} else {
+				r.logger.Info("TLS certificates reloaded", "cert_file", r.certFile)
+			}
+		}
+	}
+	return r.cert, r.clientCAs
+}
+
+func (r *certReloader) changed() bool {
+	for _, f := range r.files() {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return true
+		}
+		if (fileStamp{fi.ModTime(), fi.Size()}) != r.stamps[f] {
+			return true
+		}
+	}
+	return false
+}
+
+// tlsConfig returns a server configuration using the current certificates
+// for every handshake. Clients must present a certificate signed by one of
+// the client CAs when a client CA file is configured.
+func (r *certReloader) tlsConfig(minVersion uint16) *tls.Config {
+	return &tls.Config{
+		MinVersion: minVersion,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, clientCAs := r.current()
+			cfg := &tls.Config{
+				MinVersion:   minVersion,
+				Certificates: []tls.Certificate{*cert},
+				NextProtos:   []string{"h2", "http/1.1"},
+			}
+			if clientCAs != nil {
+				cfg.ClientCAs = clientCAs
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return cfg, nil
+		},
+	}
+}
+
+// serverTLSConfig returns the TLS configuration of the REST server, or nil
+// when TLS is disabled
+func serverTLSConfig(logger *slog.Logger, c *config.Config) (*tls.Config, error) {
+	if c.TLSCertFile == "" && c.TLSKeyFile == "" {
+		if c.TLSClientCAFile != "" {
+			return nil, errors.New("a TLS client CA requires a TLS certificate and key")
+		}
+		return nil, nil
+	}
+	if c.TLSCertFile == "" || c.TLSKeyFile == "" {
+		return nil, errors.New("both a TLS certificate and key are required")
+	}
+	minVersion, ok := tlsVersions[c.TLSMinVersion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported minimum TLS version %q, expected 1.2 or 1.3", c.TLSMinVersion)
+	}
+	r, err := newCertReloader(logger, c.TLSCertFile, c.TLSKeyFile, c.TLSClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	return r.tlsConfig(minVersion), nil
+}
@@ -0,0 +1,171 @@
+package otlpinf
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/netboxlabs/opentelemetry-infinity/config"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newTestCert(t *testing.T, cn string, parent *testCert, serial int64) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+// write stores the certificate and its key as PEM files and returns their paths
+func (c *testCert) write(t *testing.T, dir string, name string) (string, string) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	if err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return certFile, keyFile
+}
+
+// serveTLS serves a status handler with the TLS configuration and returns its URL
+func serveTLS(t *testing.T, cfg *tls.Config) string {
+	t.Helper()
+	l, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })}
+	go func() { _ = srv.Serve(l) }()
+	t.Cleanup(func() { _ = srv.Close() })
+	return "https://" + l.Addr().String()
+}
+
+func tlsClient(ca *testCert, cert *testCert) *http.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	cfg := &tls.Config{RootCAs: pool}
+	if cert != nil {
+		cfg.Certificates = []tls.Certificate{{Certificate: [][]byte{cert.der}, PrivateKey: cert.key}}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+}
+
+// The server requires client certificates signed by the client CA and serves reloaded certificates.
+func TestServerTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "otlpinf-ca", nil, 1)
+	caFile, _ := ca.write(t, dir, "ca")
+	server := newTestCert(t, "otlpinf", ca, 2)
+	certFile, keyFile := server.write(t, dir, "server")
+	client := newTestCert(t, "client", ca, 3)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg, err := serverTLSConfig(logger, &config.Config{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSClientCAFile: caFile, TLSMinVersion: "1.2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	url := serveTLS(t, cfg)
+
+	if _, err = tlsClient(ca, nil).Get(url); err == nil {
+		t.Errorf("expected clients without certificate to be rejected")
+	}
+	resp, err := tlsClient(ca, client).Get(url)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.TLS.PeerCertificates[0].SerialNumber.Int64() != 2 {
+		t.Errorf("expected the initial server certificate")
+	}
+
+	renewed := newTestCert(t, "otlpinf", ca, 4)
+	renewed.write(t, dir, "server")
+	future := time.Now().Add(time.Minute)
+	for _, f := range []string{certFile, keyFile} {
+		if err = os.Chtimes(f, future, future); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	time.Sleep(certCheckInterval)
+	resp, err = tlsClient(ca, client).Get(url)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.TLS.PeerCertificates[0].SerialNumber.Int64() != 4 {
+		t.Errorf("expected the renewed server certificate")
+	}
+}
+
+// serverTLSConfig disables TLS without certificate and rejects incomplete or invalid settings.
+func TestServerTLSConfigErrors(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	if cfg, err := serverTLSConfig(logger, &config.Config{}); cfg != nil || err != nil {
+		t.Errorf("expected TLS to be disabled, got %v, %v", cfg, err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile := newTestCert(t, "otlpinf", nil, 1).write(t, dir, "server")
+	for _, c := range []config.Config{
+		{TLSCertFile: certFile},
+		{TLSClientCAFile: certFile},
+		{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSMinVersion: "1.0"},
+		{TLSCertFile: certFile, TLSKeyFile: certFile},
+		{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSClientCAFile: keyFile},
+	} {
+		if _, err := serverTLSConfig(logger, &c); err == nil {
+			t.Errorf("expected an error for %+v", c)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package runner
+
+import (
+	"context"
+	_ "embed"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/amenzhinsky/go-memexec"
+)
+
+//go:embed otelcol-contrib
+var otelContrib []byte
+
+const embeddedCollectorName = "otelcol-contrib"
+
+// Collector represents an opentelemetry collector binary, either the embedded
+// otelcol-contrib or an external distribution on disk
+type Collector struct {
+	path string
+}
+
+// NewCollector creates a collector running the binary at path, or the
+// embedded otelcol-contrib when path is empty
+func NewCollector(path string) *Collector {
+	return &Collector{path: path}
+}
+
+// Name returns the name of the collector binary
+func (c *Collector) Name() string {
+	if c == nil || c.path == "" {
+		return embeddedCollectorName
+	}
+	return filepath.Base(c.path)
+}
+
+// GetCapabilities returns the capabilities of the collector
+func (c *Collector) GetCapabilities(logger *slog.Logger) ([]byte, error) {
+	cmd, cleanup, err := c.command(context.Background(), "components")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := cleanup(); err != nil {
+			logger.Error("failed to exit", "error", err)
+		}
+	}()
+	return cmd.Output()
+}
+
+// command prepares the collector command. The cleanup function must be called
+// once the process has been started.
+func (c *Collector) command(ctx context.Context, args ...string) (*exec.Cmd, func() error, error) {
+	if c == nil || c.path == "" {
+		exe, err := memexec.New(otelContrib)
+		if err != nil {
+			return nil, nil, err
+		}
+		return exe.CommandContext(ctx, args...), exe.Close, nil
+	}
+	cmd := exec.CommandContext(ctx, c.path, args...)
+	return cmd, func() error { return nil }, cmd.Err
+}
@@ -0,0 +1,94 @@
+package runner
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const fakeCollectorScript = `#!/bin/sh
+if [ "$1" = "components" ]; then
+	printf 'buildinfo:\n  command: otelcol-custom\n  version: 1.2.3\n'
+	exit 0
+fi
+printf 'ts\tinfo\tsrc\tEverything is ready. Begin running and processing data.\n' >&2
+exec sleep 60
+`
+
+func writeFakeCollector(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "otelcol-custom")
+	if err := os.WriteFile(path, []byte(fakeCollectorScript), 0o755); err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+	return path
+}
+
+func TestCollectorName(t *testing.T) {
+	if got := NewCollector("").Name(); got != "otelcol-contrib" {
+		t.Errorf("Expected embedded collector name, got %q", got)
+	}
+	if got := NewCollector("/opt/bin/otelcol-custom").Name(); got != "otelcol-custom" {
+		t.Errorf("Expected external collector name, got %q", got)
+	}
+}
+
+func TestExternalCollectorGetCapabilities(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: false}))
+	collector := NewCollector(writeFakeCollector(t))
+
+	caps, err := collector.GetCapabilities(logger)
+	if err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+
+	s := struct {
+		Buildinfo struct {
+			Version string
+		}
+	}{}
+	if err = yaml.Unmarshal(caps, &s); err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+	if s.Buildinfo.Version != "1.2.3" {
+		t.Errorf("Expected version 1.2.3, got %q", s.Buildinfo.Version)
+	}
+
+	_, err = NewCollector(filepath.Join(t.TempDir(), "missing")).GetCapabilities(logger)
+	if err == nil {
+		t.Errorf("Expected an error for a missing collector binary")
+	}
+}
+
+func TestExternalCollectorStartStop(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: false}))
+	runner := &Runner{
+		logger:     logger,
+		collector:  NewCollector(writeFakeCollector(t)),
+		policyName: TestPolicy,
+		policyDir:  PolicyDir,
+	}
+	if err := runner.Configure(validPolicy()); err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := runner.Start(ctx, cancel); err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+	if s := runner.GetStatus(); s.Status != running {
+		t.Errorf("Expected status to be running, but got %v", s.StatusText)
+	}
+	runner.mu.RLock()
+	path := runner.cmd.Path
+	runner.mu.RUnlock()
+	if !strings.HasSuffix(path, "otelcol-custom") {
+		t.Errorf("Expected external collector to be executed, got %q", path)
+	}
+	runner.Stop(ctx)
+}
@@ -0,0 +1,142 @@
+package runner
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLogBufferSize = 1000
+	subscriberBufferSize = 256
+)
+
+// LogRecord represents a parsed collector log line
+type LogRecord struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Source  string    `json:"source,omitempty"`
+	Message string    `json:"message"`
+	Payload any       `json:"payload,omitempty"`
+	level   slog.Level
+}
+
+// LogFilter selects the records returned by Runner.Logs
+type LogFilter struct {
+	// Since excludes records logged at or before it, unless zero
+	Since time.Time
+	// Level is the minimum level of the returned records
+	Level slog.Level
+	// Limit is the maximum number of records returned, unless zero
+	Limit int
+}
+
+func (f LogFilter) match(rec LogRecord) bool {
+	return rec.level >= f.Level && (f.Since.IsZero() || rec.Time.After(f.Since))
+}
+
+func newLogRecord(ts time.Time, msg string, level slog.Level, attrs []slog.Attr) LogRecord {
+	rec := LogRecord{Time: ts, Level: strings.ToLower(level.String()), Message: msg, level: level}
+	for _, a := range attrs {
+		switch a.Key {
+		case "collector_source":
+			rec.Source = a.Value.String()
+		case "collector_payload":
+			rec.Payload = a.Value.Any()
+		}
+	}
+	return rec
+}
+
+type subscriber struct {
+	level slog.Level
+	ch    chan LogRecord
+}
+
+// logBuffer keeps the most recent collector log records of a runner and
+// fans new records out to live subscribers
+type logBuffer struct {
+	mu          sync.RWMutex
+	records     []LogRecord
+	next        int
+	full        bool
+	subscribers map[*subscriber]struct{}
+}
+
+func newLogBuffer(size int) *logBuffer {
+	if size <= 0 {
+		size = defaultLogBufferSize
+	}
+	return &logBuffer{records: make([]LogRecord, size), subscribers: make(map[*subscriber]struct{})}
+}
+
+// add stores a record and delivers it to the subscribers. Records are dropped
+// for subscribers that are not keeping up so the log scanner never blocks.
+func (b *logBuffer) add(rec LogRecord) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.records[b.next] = rec
+	b.next = (b.next + 1) % len(b.records)
+	if b.next == 0 {
+		b.full = true
+	}
+	for s := range b.subscribers {
+		if rec.level < s.level {
+			continue
+		}
+		select {
+		case s.ch <- rec:
+		default:
+		}
+	}
+}
+
+// subscribe registers a subscriber for new records at or above level. The
+// returned function unsubscribes it; the channel is closed on unsubscribe or
+// when the buffer is closed.
+func (b *logBuffer) subscribe(level slog.Level) (<-chan LogRecord, func()) {
+	s := &subscriber{level: level, ch: make(chan LogRecord, subscriberBufferSize)}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[s] = struct{}{}
+	return s.ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[s]; ok {
+			delete(b.subscribers, s)
+			close(s.ch)
+		}
+	}
+}
+
+// close ends all live subscriptions
+func (b *logBuffer) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subscribers {
+		delete(b.subscribers, s)
+		close(s.ch)
+	}
+}
+
+// list returns the records matching the filter, oldest first. When a limit is
+// set only the most recent matching records are returned.
+func (b *logBuffer) list(f LogFilter) []LogRecord {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	ordered := b.records[:b.next]
+	if b.full {
+		ordered = append(append([]LogRecord{}, b.records[b.next:]...), b.records[:b.next]...)
+	}
+	ret := make([]LogRecord, 0, len(ordered))
+	for _, rec := range ordered {
+		if f.match(rec) {
+			ret = append(ret, rec)
+		}
+	}
+	if f.Limit > 0 && len(ret) > f.Limit {
+		ret = ret[len(ret)-f.Limit:]
+	}
+	return ret
+}
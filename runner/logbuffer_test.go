@@ -0,0 +1,129 @@
+package runner
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestLogBufferWraps(t *testing.T) {
+	b := newLogBuffer(3)
+	base := time.Now()
+	for i := 0; i < 5; i++ {
+		b.add(LogRecord{Time: base.Add(time.Duration(i) * time.Second), Message: string(rune('a' + i))})
+	}
+
+	got := b.list(LogFilter{Level: slog.LevelDebug})
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(got))
+	}
+	for i, want := range []string{"c", "d", "e"} {
+		if got[i].Message != want {
+			t.Errorf("record %d = %q, want %q", i, got[i].Message, want)
+		}
+	}
+}
+
+func TestLogBufferFilters(t *testing.T) {
+	b := newLogBuffer(10)
+	base := time.Now()
+	levels := []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError, slog.LevelInfo}
+	for i, level := range levels {
+		b.add(newLogRecord(base.Add(time.Duration(i)*time.Second), "msg", level, nil))
+	}
+
+	cases := []struct {
+		name   string
+		filter LogFilter
+		want   int
+	}{
+		{"all", LogFilter{Level: slog.LevelDebug}, 5},
+		{"default level", LogFilter{}, 4},
+		{"warn and above", LogFilter{Level: slog.LevelWarn}, 2},
+		{"since", LogFilter{Level: slog.LevelDebug, Since: base.Add(2 * time.Second)}, 2},
+		{"limit", LogFilter{Level: slog.LevelDebug, Limit: 2}, 2},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := b.list(tc.filter); len(got) != tc.want {
+				t.Errorf("Expected %d records, got %d", tc.want, len(got))
+			}
+		})
+	}
+
+	got := b.list(LogFilter{Level: slog.LevelDebug, Limit: 1})
+	if !got[0].Time.Equal(base.Add(4 * time.Second)) {
+		t.Errorf("Expected limit to keep the most recent record, got %v", got[0].Time)
+	}
+}
+
+func TestNewLogRecord(t *testing.T) {
+	msg, level, attrs := parseCollectorLog("ts\twarn\tsrc\tmessage\t{\"count\":3}")
+	rec := newLogRecord(time.Now(), msg, level, attrs)
+
+	if rec.Level != "warn" || rec.Source != "src" || rec.Message != "message" {
+		t.Errorf("Unexpected record %+v", rec)
+	}
+	if payload, ok := rec.Payload.(map[string]any); !ok || payload["count"] != float64(3) {
+		t.Errorf("Expected structured payload, got %v", rec.Payload)
+	}
+}
+
+func TestLogBufferSubscribe(t *testing.T) {
+	b := newLogBuffer(10)
+	warn, unsubscribeWarn := b.subscribe(slog.LevelWarn)
+	all, unsubscribeAll := b.subscribe(slog.LevelDebug)
+	defer unsubscribeAll()
+
+	b.add(newLogRecord(time.Now(), "info", slog.LevelInfo, nil))
+	b.add(newLogRecord(time.Now(), "error", slog.LevelError, nil))
+
+	if rec := <-warn; rec.Message != "error" {
+		t.Errorf("Expected only the error record for the warn subscriber, got %q", rec.Message)
+	}
+	for _, want := range []string{"info", "error"} {
+		if rec := <-all; rec.Message != want {
+			t.Errorf("Expected %q, got %q", want, rec.Message)
+		}
+	}
+
+	unsubscribeWarn()
+	if _, ok := <-warn; ok {
+		t.Errorf("Expected channel to be closed after unsubscribe")
+	}
+	unsubscribeWarn()
+}
+
+func TestLogBufferSlowSubscriber(t *testing.T) {
+	b := newLogBuffer(10)
+	ch, unsubscribe := b.subscribe(slog.LevelDebug)
+	defer unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 2*subscriberBufferSize; i++ {
+			b.add(newLogRecord(time.Now(), "msg", slog.LevelInfo, nil))
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected add not to block on a slow subscriber")
+	}
+	if len(ch) != subscriberBufferSize {
+		t.Errorf("Expected %d buffered records, got %d", subscriberBufferSize, len(ch))
+	}
+}
+
+func TestLogBufferClose(t *testing.T) {
+	b := newLogBuffer(10)
+	ch, unsubscribe := b.subscribe(slog.LevelDebug)
+
+	b.close()
+	if _, ok := <-ch; ok {
+		t.Errorf("Expected channel to be closed")
+	}
+	unsubscribe()
+}
@@ -0,0 +1,84 @@
+package runner
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	readyLogMessage       = "Everything is ready"
+	healthCheckID         = "health_check/otlpinf"
+	healthCheckInterval   = 100 * time.Millisecond
+	defaultStartupTimeout = 30 * time.Second
+)
+
+// isReadyLog reports whether a collector log message announces that all
+// pipelines have been started
+func isReadyLog(msg string) bool {
+	return strings.HasPrefix(msg, readyLogMessage)
+}
+
+// freeEndpoint allocates a free local TCP port for the injected health check
+func freeEndpoint() (string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = l.Close() }()
+	return l.Addr().String(), nil
+}
+
+// injectHealthCheck returns copies of the policy extensions and service
+// sections with an additional health_check extension listening on endpoint
+func injectHealthCheck(extensions map[string]interface{}, service map[string]interface{}, endpoint string) (map[string]interface{}, map[string]interface{}) {
+	ext := make(map[string]interface{}, len(extensions)+1)
+	for k, v := range extensions {
+		ext[k] = v
+	}
+	ext[healthCheckID] = map[string]interface{}{"endpoint": endpoint}
+
+	svc := make(map[string]interface{}, len(service)+1)
+	for k, v := range service {
+		svc[k] = v
+	}
+	var ids []interface{}
+	switch list := service["extensions"].(type) {
+	case []interface{}:
+		ids = append(ids, list...)
+	case []string:
+		for _, id := range list {
+			ids = append(ids, id)
+		}
+	}
+	svc["extensions"] = append(ids, healthCheckID)
+
+	return ext, svc
+}
+
+// pollHealthCheck calls ready once the health check endpoint reports the
+// collector as available, or returns when stop is closed
+func pollHealthCheck(endpoint string, ready func(), stop <-chan struct{}) {
+	client := &http.Client{Timeout: healthCheckInterval}
+	url := fmt.Sprintf("http://%s/", endpoint)
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		resp, err := client.Get(url)
+		if err != nil {
+			continue
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			ready()
+			return
+		}
+	}
+}
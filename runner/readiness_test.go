@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsReadyLog(t *testing.T) {
+	cases := map[string]bool{
+		"Everything is ready. Begin running and processing data.": true,
+		"Starting otelcol-contrib...":                             false,
+		"":                                                        false,
+	}
+	for in, want := range cases {
+		if got := isReadyLog(in); got != want {
+			t.Errorf("isReadyLog(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestInjectHealthCheck(t *testing.T) {
+	extensions := map[string]interface{}{"pprof": nil}
+	service := map[string]interface{}{
+		"extensions": []interface{}{"pprof"},
+		"pipelines":  map[string]interface{}{},
+	}
+
+	ext, svc := injectHealthCheck(extensions, service, "127.0.0.1:13133")
+
+	if _, ok := ext[healthCheckID]; !ok {
+		t.Errorf("Expected %s extension to be injected, got %v", healthCheckID, ext)
+	}
+	if _, ok := ext["pprof"]; !ok {
+		t.Errorf("Expected existing extensions to be kept, got %v", ext)
+	}
+	want := []interface{}{"pprof", healthCheckID}
+	if !reflect.DeepEqual(svc["extensions"], want) {
+		t.Errorf("Expected service extensions %v, got %v", want, svc["extensions"])
+	}
+	if _, ok := extensions[healthCheckID]; ok {
+		t.Errorf("Expected policy extensions to be left untouched")
+	}
+	if !reflect.DeepEqual(service["extensions"], []interface{}{"pprof"}) {
+		t.Errorf("Expected policy service to be left untouched, got %v", service["extensions"])
+	}
+
+	_, svc = injectHealthCheck(nil, nil, "127.0.0.1:13133")
+	if !reflect.DeepEqual(svc["extensions"], []interface{}{healthCheckID}) {
+		t.Errorf("Expected only %s in service extensions, got %v", healthCheckID, svc["extensions"])
+	}
+}
+
+func TestPollHealthCheck(t *testing.T) {
+	healthy := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		select {
+		case <-healthy:
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	ready := make(chan struct{})
+	stop := make(chan struct{})
+	defer close(stop)
+	go pollHealthCheck(strings.TrimPrefix(srv.URL, "http://"), func() { close(ready) }, stop)
+
+	select {
+	case <-ready:
+		t.Fatal("Expected collector not to be ready before the health check passes")
+	case <-time.After(3 * healthCheckInterval):
+	}
+
+	close(healthy)
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected collector to be ready once the health check passes")
+	}
+}
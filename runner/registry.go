@@ -0,0 +1,144 @@
+package runner
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/netboxlabs/opentelemetry-infinity/config"
+)
+
+// Distribution represents a named collector binary and its capabilities
+type Distribution struct {
+	Name         string
+	Version      string
+	Collector    *Collector
+	Capabilities []byte
+	components   map[string]map[string]struct{}
+}
+
+// HasComponent reports whether the distribution provides the component type
+// of the given kind, e.g. receivers/otlp. Distributions whose capabilities
+// have not been loaded are assumed to provide every component.
+func (d *Distribution) HasComponent(kind string, componentType string) bool {
+	if d.components == nil {
+		return true
+	}
+	_, ok := d.components[kind][componentType]
+	return ok
+}
+
+// parseComponents indexes the component types listed by the collector
+// components command, which are either plain names or objects with a name
+func parseComponents(caps map[string]interface{}) map[string]map[string]struct{} {
+	components := make(map[string]map[string]struct{})
+	for _, kind := range []string{"receivers", "processors", "exporters", "extensions", "connectors"} {
+		components[kind] = make(map[string]struct{})
+		list, _ := caps[kind].([]interface{})
+		for _, entry := range list {
+			switch e := entry.(type) {
+			case string:
+				components[kind][e] = struct{}{}
+			case map[string]interface{}:
+				if name, ok := e["name"].(string); ok {
+					components[kind][name] = struct{}{}
+				}
+			}
+		}
+	}
+	return components
+}
+
+// Registry holds the collector distributions policies can run on
+type Registry struct {
+	defaultName   string
+	distributions map[string]*Distribution
+	err           error
+}
+
+// NewRegistry creates a registry with the default collector, i.e. the embedded
+// otelcol-contrib or the one at config.CollectorPath, and the distributions
+// configured on disk
+func NewRegistry(c *config.Config) *Registry {
+	def := NewCollector(c.CollectorPath)
+	r := &Registry{
+		defaultName:   def.Name(),
+		distributions: map[string]*Distribution{def.Name(): {Name: def.Name(), Collector: def}},
+	}
+	for name, path := range c.Distributions {
+		if _, ok := r.distributions[name]; ok {
+			r.err = fmt.Errorf("distribution %q conflicts with the default collector", name)
+			continue
+		}
+		r.distributions[name] = &Distribution{Name: name, Collector: NewCollector(path)}
+	}
+	return r
+}
+
+// Load caches the capabilities and version of every distribution
+func (r *Registry) Load(logger *slog.Logger) error {
+	if r.err != nil {
+		return r.err
+	}
+	for _, name := range r.Names() {
+		d := r.distributions[name]
+		caps, err := d.Collector.GetCapabilities(logger)
+		if err != nil {
+			return fmt.Errorf("distribution %q: %w", name, err)
+		}
+		s := struct {
+			Buildinfo struct {
+				Version string
+			}
+		}{}
+		if err = yaml.Unmarshal(caps, &s); err != nil {
+			return fmt.Errorf("distribution %q: %w", name, err)
+		}
+		var all map[string]interface{}
+		if err = yaml.Unmarshal(caps, &all); err != nil {
+			return fmt.Errorf("distribution %q: %w", name, err)
+		}
+		d.Capabilities = caps
+		d.Version = s.Buildinfo.Version
+		d.components = parseComponents(all)
+	}
+	return nil
+}
+
+// Default returns the default distribution
+func (r *Registry) Default() *Distribution {
+	return r.distributions[r.defaultName]
+}
+
+// Get returns the named distribution, or the default one when name is empty
+func (r *Registry) Get(name string) (*Distribution, error) {
+	if name == "" {
+		return r.Default(), nil
+	}
+	d, ok := r.distributions[name]
+	if !ok {
+		return nil, fmt.Errorf("distribution %q not found", name)
+	}
+	return d, nil
+}
+
+// Names returns the sorted names of all distributions
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.distributions))
+	for name := range r.distributions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Versions returns the version of every distribution by name
+func (r *Registry) Versions() map[string]string {
+	versions := make(map[string]string, len(r.distributions))
+	for name, d := range r.distributions {
+		versions[name] = d.Version
+	}
+	return versions
+}
@@ -0,0 +1,62 @@
+package runner
+
+import (
+	"log/slog"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/netboxlabs/opentelemetry-infinity/config"
+)
+
+func TestRegistryLoad(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: false}))
+	reg := NewRegistry(&config.Config{Distributions: map[string]string{"custom": writeFakeCollector(t)}})
+
+	if err := reg.Load(logger); err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+
+	if got := reg.Names(); !reflect.DeepEqual(got, []string{"custom", "otelcol-contrib"}) {
+		t.Errorf("Unexpected distribution names %v", got)
+	}
+	d, err := reg.Get("")
+	if err != nil || d.Name != "otelcol-contrib" || len(d.Capabilities) == 0 {
+		t.Errorf("Expected the loaded embedded collector as default, got %+v, %v", d, err)
+	}
+	d, err = reg.Get("custom")
+	if err != nil || d.Version != "1.2.3" {
+		t.Errorf("Expected custom distribution version 1.2.3, got %+v, %v", d, err)
+	}
+	if reg.Versions()["custom"] != "1.2.3" {
+		t.Errorf("Unexpected versions %v", reg.Versions())
+	}
+	if _, err = reg.Get("missing"); err == nil {
+		t.Errorf("Expected an error for an unknown distribution")
+	}
+}
+
+func TestRegistryDefaultCollectorPath(t *testing.T) {
+	reg := NewRegistry(&config.Config{CollectorPath: "/opt/bin/otelcol-custom"})
+
+	if d := reg.Default(); d.Name != "otelcol-custom" {
+		t.Errorf("Expected the collector path to be the default distribution, got %q", d.Name)
+	}
+	if _, err := reg.Get("otelcol-contrib"); err == nil {
+		t.Errorf("Expected the embedded collector to be replaced by the collector path")
+	}
+}
+
+func TestRegistryLoadErrors(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: false}))
+
+	reg := NewRegistry(&config.Config{Distributions: map[string]string{"otelcol-contrib": writeFakeCollector(t)}})
+	if err := reg.Load(logger); err == nil {
+		t.Errorf("Expected an error for a distribution named like the default collector")
+	}
+
+	reg = NewRegistry(&config.Config{Distributions: map[string]string{"broken": "/nonexistent/otelcol"}})
+	if err := reg.Load(logger); err == nil {
+		t.Errorf("Expected an error for a missing distribution binary")
+	}
+}
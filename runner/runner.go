@@ -3,41 +3,58 @@ package runner
 import (
 	"bufio"
 	"context"
-	_ "embed"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"math/rand/v2"
 	"os"
 	"os/exec"
 	"regexp"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
-	"github.com/amenzhinsky/go-memexec"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/yaml.v3"
 
 	"github.com/netboxlabs/opentelemetry-infinity/config"
 )
 
-//go:embed otelcol-contrib
-var otelContrib []byte
+var tracer = otel.Tracer("github.com/netboxlabs/opentelemetry-infinity/runner")
 
-type status int
+// ValidationError is returned when the collector rejects a policy
+type ValidationError struct {
+	Details []string
+}
 
-const (
-	unknown status = iota
-	running
-	runnerError
-	offline
-)
+func newValidationError(output string) *ValidationError {
+	details := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || shouldSuppressCollectorLog(line) {
+			continue
+		}
+		details = append(details, line)
+	}
+	return &ValidationError{Details: details}
+}
 
-var mapStatus = map[status]string{
-	unknown:     "unknown",
-	running:     "running",
-	runnerError: "runner_error",
-	offline:     "offline",
+func (e *ValidationError) Error() string {
+	return "invalid policy: " + strings.Join(e.Details, "; ")
 }
 
+// ErrNotCrashLooping is returned when resetting a runner that is not in the
+// crash loop state
+var ErrNotCrashLooping = errors.New("runner is not in crash loop state")
+
+var logSanitizer = regexp.MustCompile("[^a-zA-Z0-9:(), ]+")
+
 // State represents the state of the runner
 type State struct {
 	Status        status    `yaml:"-"`
@@ -47,11 +64,14 @@ type State struct {
 	LastLog       string    `yaml:"-"`
 	LastError     string    `yaml:"last_error"`
 	LastRestartTS time.Time `yaml:"last_restart_time"`
+	ExitCode      int       `yaml:"exit_code"`
+	ExitSignal    string    `yaml:"exit_signal,omitempty"`
 }
 
 // Runner is responsible for executing opentelemetry policies
 type Runner struct {
 	logger        *slog.Logger
+	collector     *Collector
 	policyName    string
 	policyDir     string
 	policyFile    string
@@ -59,43 +79,61 @@ type Runner struct {
 	sets          []string
 	options       []string
 	selfTelemetry bool
-	state         State
-	cancelFunc    context.CancelFunc
-	ctx           context.Context
-	cmd           *exec.Cmd
-	errChan       chan string
-}
 
-// GetCapabilities returns the capabilities of the runner
-func GetCapabilities(logger *slog.Logger) ([]byte, error) {
-	exe, err := memexec.New(otelContrib)
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		if err := exe.Close(); err != nil {
-			logger.Error("failed to exit", "error", err)
-		}
-	}()
-	cmd := exe.Command("components")
-	ret, err := cmd.Output()
-	if err != nil {
-		return nil, err
-	}
-	return ret, nil
+	restartBackoff    time.Duration
+	restartBackoffMax time.Duration
+	restartJitter     float64
+	maxRestarts       int
+	restartWindow     time.Duration
+	resetChan         chan struct{}
+	drainTimeout      time.Duration
+	done              chan struct{}
+	startupTimeout    time.Duration
+	healthCheck       bool
+	healthEndpoint    string
+	logs              *logBuffer
+	onLaunch          func(startup time.Duration, err error)
+
+	mu         sync.RWMutex
+	state      State
+	logCounts  map[slog.Level]int64
+	history    []Transition
+	span       trace.Span
+	cancelFunc context.CancelFunc
+	ctx        context.Context
+	cmd        *exec.Cmd
 }
 
 // NewRunner creates a new runner
 func NewRunner(logger *slog.Logger, policyName string, policyDir string, config *config.Config) *Runner {
 	return &Runner{
-		logger: logger, policyName: policyName, policyDir: policyDir,
-		selfTelemetry: config.SelfTelemetry, sets: config.Set, featureGates: config.FeatureGates, errChan: make(chan string),
+		logger: logger, collector: NewCollector(config.CollectorPath), policyName: policyName, policyDir: policyDir,
+		selfTelemetry: config.SelfTelemetry, sets: config.Set, featureGates: config.FeatureGates,
+		restartBackoff: config.RestartBackoff, restartBackoffMax: config.RestartBackoffMax, restartJitter: config.RestartJitter,
+		maxRestarts: config.MaxRestarts, restartWindow: config.RestartWindow, drainTimeout: config.DrainTimeout,
+		startupTimeout: config.StartupTimeout, healthCheck: config.HealthCheck,
+		logs:  newLogBuffer(config.LogBufferSize),
+		state: State{Status: pending, StatusText: mapStatus[pending]},
 	}
 }
 
 // Configure configures the runner with the given policy
 func (r *Runner) Configure(c *config.Policy) error {
-	b, err := yaml.Marshal(&c)
+	if c.Otlpinf.DrainTimeout > 0 {
+		r.drainTimeout = c.Otlpinf.DrainTimeout
+	}
+
+	collector := *c
+	collector.Otlpinf = config.PolicyOptions{}
+	if r.healthCheck {
+		endpoint, err := freeEndpoint()
+		if err != nil {
+			return err
+		}
+		r.healthEndpoint = endpoint
+		collector.Extensions, collector.Service = injectHealthCheck(c.Extensions, c.Service, endpoint)
+	}
+	b, err := yaml.Marshal(&collector)
 	if err != nil {
 		return err
 	}
@@ -133,100 +171,447 @@ func (r *Runner) Configure(c *config.Policy) error {
 	return nil
 }
 
-// Start starts the runner
-func (r *Runner) Start(ctx context.Context, cancelFunc context.CancelFunc) error {
-	r.cancelFunc = cancelFunc
-	r.ctx = ctx
-
-	exe, err := memexec.New(otelContrib)
+// Validate checks the configured policy with the collector validate command,
+// using the same options the collector is started with
+func (r *Runner) Validate(ctx context.Context) error {
+	cmd, cleanup, err := r.collector.command(ctx, append([]string{"validate"}, r.options...)...)
 	if err != nil {
 		return err
 	}
 	defer func() {
-		if err := exe.Close(); err != nil {
+		if err := cleanup(); err != nil {
 			r.logger.Error("failed to exit", "error", err)
 		}
 	}()
+	if cmd.Err != nil {
+		return cmd.Err
+	}
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return newValidationError(string(out))
+	}
+	return err
+}
+
+// Adopt takes over the restart history, status transitions and buffered logs
+// of the runner a policy was previously executed by. It must be called before
+// Start.
+func (r *Runner) Adopt(prev *Runner) {
+	s := prev.GetStatus()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.state.RestartCount = s.RestartCount
+	r.state.LastRestartTS = s.LastRestartTS
+	if prev.logs != nil {
+		r.logs = prev.logs
+	}
+	prev.mu.RLock()
+	defer prev.mu.RUnlock()
+	r.history = append(append([]Transition(nil), prev.history...), r.history...)
+	if len(r.history) > maxTransitions {
+		r.history = r.history[len(r.history)-maxTransitions:]
+	}
+	for level, n := range prev.logCounts {
+		if r.logCounts == nil {
+			r.logCounts = make(map[slog.Level]int64)
+		}
+		r.logCounts[level] += n
+	}
+}
 
-	r.cmd = exe.CommandContext(ctx, r.options...)
-	if r.cmd.Err != nil {
-		return r.cmd.Err
+// Discard removes the policy file of a runner that is not going to be started
+func (r *Runner) Discard() error {
+	if r.policyFile == "" {
+		return nil
 	}
-	stderr, err := r.cmd.StderrPipe()
+	return os.Remove(r.policyFile)
+}
+
+// Start starts the runner and supervises the collector process
+func (r *Runner) Start(ctx context.Context, cancelFunc context.CancelFunc) error {
+	r.cancelFunc = cancelFunc
+	r.ctx = ctx
+	r.resetChan = make(chan struct{}, 1)
+	r.setSpan(trace.SpanFromContext(ctx))
+
+	exited, err := r.timedLaunch()
 	if err != nil {
+		r.setError(failed, err)
 		return err
 	}
-	go func() {
-		scanner := bufio.NewScanner(stderr)
-		for scanner.Scan() {
-			line := scanner.Text()
-			if shouldSuppressCollectorLog(line) {
-				continue
-			}
-			r.state.LastLog = line
-			msg, level, attrs := parseCollectorLog(line)
-			attrs = append([]slog.Attr{slog.String("policy", r.policyName)}, attrs...)
-			r.logger.LogAttrs(r.ctx, level, msg, attrs...)
-			if r.cmd.Err != nil {
-				r.errChan <- r.state.LastLog
-			}
+
+	r.done = make(chan struct{})
+	go r.supervise(exited)
+
+	return nil
+}
+
+// Stop stops the runner. The collector is sent SIGTERM so it can drain its
+// pipelines and is killed if it has not exited within the drain timeout. The
+// stop is traced as a Runner.Stop span, linked to the start of the runner,
+// holding the state transitions of the stop.
+func (r *Runner) Stop(ctx context.Context) {
+	r.logger.Info("routine call to stop runner", slog.Any("routine", ctx.Value("routine")))
+	opts := []trace.SpanStartOption{trace.WithAttributes(attribute.String("policy", r.policyName))}
+	if r.ctx != nil {
+		opts = append(opts, trace.WithLinks(trace.LinkFromContext(r.ctx)))
+	}
+	_, span := tracer.Start(ctx, "Runner.Stop", opts...)
+	defer span.End()
+	r.setSpan(span)
+	if r.done != nil {
+		r.setStatus(stopping, "stop requested")
+	}
+	r.cancelFunc()
+	if r.logs != nil {
+		defer r.logs.close()
+	}
+	if r.done != nil {
+		<-r.done
+		return
+	}
+	r.setStatus(stopped, "stop requested")
+	r.logger.Info("runner process stopped", slog.String("policy", r.policyName))
+}
+
+// Reset relaunches the collector of a runner in the crash loop state
+func (r *Runner) Reset() error {
+	if r.GetStatus().Status != crashLoop {
+		return ErrNotCrashLooping
+	}
+	select {
+	case r.resetChan <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// launch starts a collector process and waits until it reports readiness,
+// either through its logs or the injected health check. Without the health
+// check, a collector that is still running at the startup timeout is
+// considered ready, as collectors logging above info level never log their
+// readiness. The returned channel receives the result of cmd.Wait once the
+// process exits.
+func (r *Runner) launch() (<-chan error, error) {
+	cmd, cleanup, err := r.collector.command(r.ctx, r.options...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := cleanup(); err != nil {
+			r.logger.Error("failed to exit", "error", err)
 		}
 	}()
-	if err = r.cmd.Start(); err != nil {
-		return err
+	if cmd.Err != nil {
+		return nil, cmd.Err
 	}
-	go func() {
-		if err := r.cmd.Wait(); err != nil {
-			r.errChan <- r.state.LastLog
-			close(r.errChan)
+	if r.drainTimeout > 0 {
+		cmd.Cancel = func() error {
+			return cmd.Process.Signal(syscall.SIGTERM)
 		}
+		cmd.WaitDelay = r.drainTimeout
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.cmd = cmd
+	r.state.startTime = time.Now()
+	r.mu.Unlock()
+	r.setStatus(starting, fmt.Sprintf("collector launched with pid %d", cmd.Process.Pid))
+
+	readyChan := make(chan struct{})
+	ready := sync.OnceFunc(func() { close(readyChan) })
+	exited := make(chan error, 1)
+	go func() {
+		r.scanLogs(stderr, ready)
+		err := cmd.Wait()
+		r.recordExit(cmd.ProcessState)
+		exited <- err
 	}()
 
-	reg, _ := regexp.Compile("[^a-zA-Z0-9:(), ]+")
+	stopPolling := make(chan struct{})
+	defer close(stopPolling)
+	if r.healthEndpoint != "" {
+		go pollHealthCheck(r.healthEndpoint, ready, stopPolling)
+	}
 
-	r.state.startTime = time.Now()
-	ctxTimeout, cancel := context.WithTimeout(r.ctx, 1*time.Second)
-	defer cancel()
+	timeout := r.startupTimeout
+	if timeout <= 0 {
+		timeout = defaultStartupTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
 	select {
-	case line := <-r.errChan:
-		return errors.New(string(append([]byte("otelcol-contrib - "), reg.ReplaceAllString(line, "")...)))
-	case <-ctxTimeout.Done():
-		r.setStatus(running)
-		r.logger.Info("runner proccess started successfully", slog.String("policy", r.policyName), slog.Any("pid", r.cmd.Process.Pid))
+	case <-exited:
+		return nil, r.collectorError()
+	case <-timer.C:
+		if r.healthEndpoint != "" {
+			if err := cmd.Process.Kill(); err != nil {
+				r.logger.Error("failed to kill collector", slog.String("policy", r.policyName), slog.String("error", err.Error()))
+			}
+			<-exited
+			return nil, fmt.Errorf("%s - not ready after %v", r.collector.Name(), timeout)
+		}
+		r.setStatus(running, fmt.Sprintf("collector still running after %v", timeout))
+		r.logger.Warn("runner process did not report readiness and is considered ready as it is still running", slog.String("policy", r.policyName),
+			slog.Any("pid", cmd.Process.Pid), slog.Duration("startup_timeout", timeout))
+	case <-readyChan:
+		r.setStatus(running, "collector is ready")
+		r.logger.Info("runner proccess started successfully", slog.String("policy", r.policyName), slog.Any("pid", cmd.Process.Pid),
+			slog.Duration("startup_time", time.Since(r.GetStatus().startTime)))
 	}
 
-	go func() {
+	return exited, nil
+}
+
+// timedLaunch launches a collector and reports how long it took to become
+// ready, or to fail, to the launch hook
+func (r *Runner) timedLaunch() (<-chan error, error) {
+	begin := time.Now()
+	exited, err := r.launch()
+	if r.onLaunch != nil {
+		r.onLaunch(time.Since(begin), err)
+	}
+	return exited, err
+}
+
+// supervise watches the collector process and relaunches it with exponential
+// backoff whenever it exits while the runner context is still active. When the
+// collector restarts more than maxRestarts times within restartWindow the
+// runner enters the crash loop state and waits for a manual Reset.
+func (r *Runner) supervise(exited <-chan error) {
+	defer close(r.done)
+	attempt := 0
+	var restarts []time.Time
+	for {
+		select {
+		case <-exited:
+		case <-r.ctx.Done():
+			<-exited
+			r.stopped()
+			return
+		}
+		if r.ctx.Err() != nil {
+			r.stopped()
+			return
+		}
+
+		err := r.collectorError()
+		_, span := tracer.Start(r.ctx, "Runner.restart", trace.WithNewRoot(),
+			trace.WithLinks(trace.LinkFromContext(r.ctx)), trace.WithAttributes(attribute.String("policy", r.policyName)))
+		r.setSpan(span)
+		r.setError(degraded, err)
+		r.logger.Warn("runner process exited", slog.String("policy", r.policyName), slog.String("error", err.Error()))
+
+		if time.Since(r.GetStatus().startTime) >= r.restartBackoffMax {
+			attempt = 0
+		}
 		for {
-			select {
-			case line := <-r.errChan:
-				r.state.LastError = string(append([]byte("otelcol-contrib - "), reg.ReplaceAllString(line, "")...))
-				r.setStatus(runnerError)
-			case <-r.ctx.Done():
-				r.Stop(r.ctx)
-				return
+			restarts = r.recentRestarts(restarts, time.Now())
+			if r.maxRestarts > 0 && len(restarts) >= r.maxRestarts {
+				r.setStatus(crashLoop, fmt.Sprintf("restarted %d times within %v", len(restarts), r.restartWindow))
+				r.logger.Error("runner process is crash looping, restarts suspended until reset", slog.String("policy", r.policyName),
+					slog.Int("restarts", len(restarts)), slog.Duration("window", r.restartWindow))
+				select {
+				case <-r.resetChan:
+					restarts = nil
+					attempt = 0
+					span.AddEvent("crash_loop_reset")
+					r.logger.Info("runner crash loop reset", slog.String("policy", r.policyName))
+				case <-r.ctx.Done():
+					r.stopped()
+					span.End()
+					return
+				}
+			} else {
+				delay := r.backoff(attempt)
+				attempt++
+				r.logger.Info("restarting runner process", slog.String("policy", r.policyName), slog.Duration("backoff", delay))
+				select {
+				case <-time.After(delay):
+				case <-r.ctx.Done():
+					r.stopped()
+					span.End()
+					return
+				}
 			}
+
+			restarts = append(restarts, time.Now())
+			r.recordRestart()
+			if exited, err = r.timedLaunch(); err == nil {
+				break
+			}
+			r.setError(degraded, err)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			r.logger.Warn("runner process failed to restart", slog.String("policy", r.policyName), slog.String("error", err.Error()))
 		}
-	}()
+		span.End()
+	}
+}
 
-	return nil
+func (r *Runner) stopped() {
+	s := r.GetStatus()
+	reason := fmt.Sprintf("collector exited with code %d", s.ExitCode)
+	if s.ExitSignal != "" {
+		reason = "collector exited on signal " + s.ExitSignal
+	}
+	r.setStatus(stopped, reason)
+	r.logger.Info("runner process stopped", slog.String("policy", r.policyName),
+		slog.Int("exit_code", s.ExitCode), slog.String("exit_signal", s.ExitSignal))
 }
 
-// Stop stops the runner
-func (r *Runner) Stop(ctx context.Context) {
-	r.logger.Info("routine call to stop runner", slog.Any("routine", ctx.Value("routine")))
-	defer r.cancelFunc()
-	r.setStatus(offline)
-	r.logger.Info("runner process stopped", slog.String("policy", r.policyName))
+// recentRestarts drops the restarts that happened before the restart window.
+func (r *Runner) recentRestarts(restarts []time.Time, now time.Time) []time.Time {
+	recent := restarts[:0]
+	for _, ts := range restarts {
+		if now.Sub(ts) < r.restartWindow {
+			recent = append(recent, ts)
+		}
+	}
+	return recent
+}
+
+// backoff returns the delay before the given restart attempt, doubling the
+// initial backoff on each attempt up to the configured maximum and applying
+// a random jitter of +/- restartJitter.
+func (r *Runner) backoff(attempt int) time.Duration {
+	d := r.restartBackoff
+	for i := 0; i < attempt && d < r.restartBackoffMax; i++ {
+		d *= 2
+	}
+	if r.restartBackoffMax > 0 && d > r.restartBackoffMax {
+		d = r.restartBackoffMax
+	}
+	if r.restartJitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * r.restartJitter * float64(d))
+	}
+	return d
+}
+
+func (r *Runner) scanLogs(stderr io.Reader, ready func()) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if shouldSuppressCollectorLog(line) {
+			continue
+		}
+		msg, level, attrs := parseCollectorLog(line)
+		r.mu.Lock()
+		r.state.LastLog = line
+		if r.logCounts == nil {
+			r.logCounts = make(map[slog.Level]int64)
+		}
+		r.logCounts[level]++
+		r.mu.Unlock()
+		if r.logs != nil {
+			r.logs.add(newLogRecord(time.Now(), msg, level, attrs))
+		}
+		attrs = append([]slog.Attr{slog.String("policy", r.policyName)}, attrs...)
+		r.logger.LogAttrs(r.ctx, level, msg, attrs...)
+		if isReadyLog(msg) {
+			ready()
+		}
+	}
+}
+
+func (r *Runner) collectorError() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return errors.New(r.collector.Name() + " - " + logSanitizer.ReplaceAllString(r.state.LastLog, ""))
 }
 
 // GetStatus returns the status of the runner
 func (r *Runner) GetStatus() State {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	return r.state
 }
 
-func (r *Runner) setStatus(s status) {
-	r.state.Status = s
-	r.state.StatusText = mapStatus[s]
+// SetCollector sets the collector binary the runner executes
+func (r *Runner) SetCollector(c *Collector) {
+	r.collector = c
+}
+
+// OnLaunch sets a function called every time a collector is launched, with
+// the time it took to become ready or fail. It must be called before Start.
+func (r *Runner) OnLaunch(f func(startup time.Duration, err error)) {
+	r.onLaunch = f
+}
+
+// Uptime returns how long the collector has been running since it was last
+// launched, or zero when it is not running
+func (r *Runner) Uptime() time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.state.Status != running {
+		return 0
+	}
+	return time.Since(r.state.startTime)
+}
+
+// LogCounts returns the number of collector log lines by level
+func (r *Runner) LogCounts() map[string]int64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	counts := make(map[string]int64, len(r.logCounts))
+	for level, n := range r.logCounts {
+		counts[strings.ToLower(level.String())] = n
+	}
+	return counts
+}
+
+// Logs returns the buffered collector log records matching the filter
+func (r *Runner) Logs(f LogFilter) []LogRecord {
+	if r.logs == nil {
+		return []LogRecord{}
+	}
+	return r.logs.list(f)
+}
+
+// SubscribeLogs streams new collector log records at or above level until the
+// returned function is called or the runner is stopped
+func (r *Runner) SubscribeLogs(level slog.Level) (<-chan LogRecord, func()) {
+	if r.logs == nil {
+		ch := make(chan LogRecord)
+		close(ch)
+		return ch, func() {}
+	}
+	return r.logs.subscribe(level)
+}
+
+// setSpan sets the span state transitions are recorded in
+func (r *Runner) setSpan(span trace.Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.span = span
+}
+
+func (r *Runner) recordExit(ps *os.ProcessState) {
+	if ps == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.state.ExitCode = ps.ExitCode()
+	r.state.ExitSignal = ""
+	if ws, ok := ps.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		r.state.ExitSignal = ws.Signal().String()
+	}
+}
+
+func (r *Runner) recordRestart() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.state.RestartCount++
+	r.state.LastRestartTS = time.Now()
 }
 
 func parseCollectorLog(line string) (string, slog.Level, []slog.Attr) {
@@ -236,6 +621,11 @@ func parseCollectorLog(line string) (string, slog.Level, []slog.Attr) {
 	if line == "" {
 		return msg, level, nil
 	}
+	if strings.HasPrefix(line, "{") {
+		if msg, level, attrs, ok := parseCollectorJSONLog(line); ok {
+			return msg, level, attrs
+		}
+	}
 
 	parts := strings.SplitN(line, "\t", 5)
 	if len(parts) == 1 {
@@ -279,6 +669,34 @@ func parseCollectorLog(line string) (string, slog.Level, []slog.Attr) {
 	return strings.TrimSpace(msg), level, attrs
 }
 
+// parseCollectorJSONLog parses a log line of a collector configured with the
+// json encoding, whose other fields are kept as the payload
+func parseCollectorJSONLog(line string) (string, slog.Level, []slog.Attr, bool) {
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return "", slog.LevelInfo, nil, false
+	}
+	msg, ok := fields["msg"].(string)
+	if !ok {
+		return "", slog.LevelInfo, nil, false
+	}
+	level := slog.LevelInfo
+	if lvl, ok := fields["level"].(string); ok {
+		level = mapCollectorLevel(lvl)
+	}
+	var attrs []slog.Attr
+	if src, ok := fields["caller"].(string); ok && src != "" {
+		attrs = append(attrs, slog.String("collector_source", src))
+	}
+	for _, k := range []string{"msg", "level", "ts", "caller"} {
+		delete(fields, k)
+	}
+	if len(fields) > 0 {
+		attrs = append(attrs, slog.Any("collector_payload", fields))
+	}
+	return strings.TrimSpace(msg), level, attrs, true
+}
+
 func shouldSuppressCollectorLog(line string) bool {
 	trimmed := strings.TrimSpace(line)
 	if trimmed == "" {
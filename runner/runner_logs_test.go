@@ -1,8 +1,13 @@
 package runner
 
 import (
+	"context"
+	"io"
 	"log/slog"
+	"strings"
 	"testing"
+
+	"github.com/netboxlabs/opentelemetry-infinity/config"
 )
 
 func hasAttr(attrs []slog.Attr, key string) bool {
@@ -106,6 +111,17 @@ func TestParseCollectorLog(t *testing.T) {
 		}
 	})
 
+	t.Run("json encoded line", func(t *testing.T) {
+		line := `{"level":"warn","ts":"2024-01-01T00:00:00Z","caller":"service.go:1","msg":"Everything is ready. Begin running and processing data.","count":3}`
+		msg, level, attrs := parseCollectorLog(line)
+		if !isReadyLog(msg) || level != slog.LevelWarn {
+			t.Errorf("got (%q, %v)", msg, level)
+		}
+		if !hasAttr(attrs, "collector_source") || !hasAttr(attrs, "collector_payload") {
+			t.Errorf("expected collector_source and collector_payload attrs, got %v", attrs)
+		}
+	})
+
 	t.Run("non-json payload kept as string", func(t *testing.T) {
 		line := "ts\tinfo\tsrc\tmessage\tplain payload text"
 		_, _, attrs := parseCollectorLog(line)
@@ -114,3 +130,23 @@ func TestParseCollectorLog(t *testing.T) {
 		}
 	})
 }
+
+// Collector log lines are counted by level and the counts are adopted by the next runner.
+func TestRunnerLogCounts(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	r := NewRunner(logger, "p1", t.TempDir(), &config.Config{})
+	r.ctx = context.Background()
+	lines := "ts\tinfo\tsrc\tstarting\nts\twarn\tsrc\tslow exporter\nts\tinfo\tsrc\tEverything is ready.\n"
+	r.scanLogs(strings.NewReader(lines), func() {})
+
+	counts := r.LogCounts()
+	if counts["info"] != 2 || counts["warn"] != 1 {
+		t.Fatalf("expected 2 info and 1 warn lines, got %v", counts)
+	}
+
+	next := NewRunner(logger, "p1", t.TempDir(), &config.Config{})
+	next.Adopt(r)
+	if got := next.LogCounts(); got["info"] != 2 || got["warn"] != 1 {
+		t.Errorf("expected the counts to be adopted, got %v", got)
+	}
+}
@@ -2,12 +2,15 @@ package runner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
@@ -152,11 +155,7 @@ func TestRunnerStartStop(t *testing.T) {
 		featureGates:  "awsemf.nodimrollupdefault,exporter.datadogexporter.DisableAPMStats",
 		sets:          []string{"--set=set1=set1", "--set=set2=set2"},
 	}
-	config := &config.Policy{
-		Receivers: map[string]interface{}{
-			"policy": "value1",
-		},
-	}
+	config := validPolicy()
 
 	// Act
 	err := runner.Configure(config)
@@ -173,8 +172,429 @@ func TestRunnerStartStop(t *testing.T) {
 	runner.Stop(ctx)
 
 	s := runner.GetStatus()
-	if mapStatus[s.Status] != "offline" {
-		t.Errorf("Expected status to be offline, but got %v", mapStatus[s.Status])
+	if mapStatus[s.Status] != "stopped" {
+		t.Errorf("Expected status to be stopped, but got %v", mapStatus[s.Status])
+	}
+	if s.ExitSignal != "killed" {
+		t.Errorf("Expected exit signal to be killed, but got %q", s.ExitSignal)
+	}
+}
+
+func TestRunnerGracefulStop(t *testing.T) {
+	// Arrange
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: false}))
+	runner := &Runner{
+		logger:       logger,
+		policyName:   TestPolicy,
+		policyDir:    PolicyDir,
+		drainTimeout: time.Second,
+	}
+	policy := validPolicy()
+	policy.Otlpinf.DrainTimeout = 5 * time.Second
+
+	// Act
+	err := runner.Configure(policy)
+	if err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+	if runner.drainTimeout != 5*time.Second {
+		t.Errorf("Expected policy drain timeout to override the default, but got %v", runner.drainTimeout)
+	}
+	b, err := os.ReadFile(runner.policyFile)
+	if err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+	if strings.Contains(string(b), "otlpinf") {
+		t.Errorf("Expected otlpinf options to be removed from collector config, but got %s", b)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err = runner.Start(ctx, cancel); err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+	runner.Stop(ctx)
+
+	// Assert
+	s := runner.GetStatus()
+	if s.Status != stopped {
+		t.Errorf("Expected status to be stopped, but got %v", s.StatusText)
+	}
+	if s.ExitCode != 0 || s.ExitSignal != "" {
+		t.Errorf("Expected collector to exit cleanly, but got code %d signal %q", s.ExitCode, s.ExitSignal)
+	}
+}
+
+func TestRunnerValidate(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: false}))
+	runner := &Runner{
+		logger:       logger,
+		policyName:   TestPolicy,
+		policyDir:    PolicyDir,
+		featureGates: "awsemf.nodimrollupdefault",
+	}
+
+	// Valid policy
+	if err := runner.Configure(validPolicy()); err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+	if err := runner.Validate(context.Background()); err != nil {
+		t.Errorf(ErrorMessage, err)
+	}
+
+	// Invalid policy
+	policy := validPolicy()
+	policy.Receivers = map[string]interface{}{"invalid": nil}
+	if err := runner.Configure(policy); err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+	err := runner.Validate(context.Background())
+	var vErr *ValidationError
+	if !errors.As(err, &vErr) {
+		t.Fatalf("Expected a validation error, but got %v", err)
+	}
+	if len(vErr.Details) == 0 || !strings.Contains(strings.Join(vErr.Details, "\n"), `"invalid"`) {
+		t.Errorf("Expected unmangled collector output in details, but got %v", vErr.Details)
+	}
+}
+
+func TestNewValidationError(t *testing.T) {
+	err := newValidationError("\nError: invalid configuration: receivers: \"invalid\"\n  \n2024\twarn\tx\tFailed to get executable path: lstat /memfd:x\n")
+
+	want := []string{`Error: invalid configuration: receivers: "invalid"`}
+	if !reflect.DeepEqual(err.Details, want) {
+		t.Errorf("Expected details %v, got %v", want, err.Details)
+	}
+	if !strings.Contains(err.Error(), want[0]) {
+		t.Errorf("Expected error message to contain the details, got %q", err.Error())
+	}
+}
+
+func TestRunnerStartupTimeout(t *testing.T) {
+	// Arrange
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: false}))
+	runner := &Runner{
+		logger:         logger,
+		policyName:     TestPolicy,
+		policyDir:      PolicyDir,
+		startupTimeout: time.Nanosecond,
+		healthCheck:    true,
+	}
+	if err := runner.Configure(validPolicy()); err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+
+	// Act
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := runner.Start(ctx, cancel)
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "not ready after") {
+		t.Errorf("Expected a 'not ready after' error, but got: %v", err)
+	}
+}
+
+// Without the health check, a collector that never logs its readiness is ready once it is still running at the startup timeout.
+func TestRunnerStartupTimeoutStillRunning(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: false}))
+	path := filepath.Join(t.TempDir(), "otelcol-quiet")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nexec sleep 60\n"), 0o755); err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+	runner := &Runner{
+		logger:         logger,
+		collector:      NewCollector(path),
+		policyName:     TestPolicy,
+		policyDir:      PolicyDir,
+		startupTimeout: 200 * time.Millisecond,
+	}
+	if err := runner.Configure(validPolicy()); err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := runner.Start(ctx, cancel); err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+	defer runner.Stop(ctx)
+	transitions := runner.Transitions()
+	if s := runner.GetStatus(); s.Status != running || !strings.Contains(transitions[len(transitions)-1].Reason, "still running") {
+		t.Errorf("Expected the collector to be considered ready, got %v: %+v", s.StatusText, transitions)
+	}
+}
+
+func TestRunnerConfigureHealthCheck(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: false}))
+	runner := &Runner{
+		logger:      logger,
+		policyName:  TestPolicy,
+		policyDir:   PolicyDir,
+		healthCheck: true,
+	}
+	policy := validPolicy()
+
+	if err := runner.Configure(policy); err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+
+	if runner.healthEndpoint == "" {
+		t.Fatalf("Expected a health check endpoint to be allocated")
+	}
+	b, err := os.ReadFile(runner.policyFile)
+	if err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+	if !strings.Contains(string(b), runner.healthEndpoint) || !strings.Contains(string(b), healthCheckID) {
+		t.Errorf("Expected %s on %s in collector config, but got %s", healthCheckID, runner.healthEndpoint, b)
+	}
+	if _, ok := policy.Extensions[healthCheckID]; ok {
+		t.Errorf("Expected policy to be left untouched")
+	}
+}
+
+func TestRunnerRestart(t *testing.T) {
+	// Arrange
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: false}))
+	runner := &Runner{
+		logger:            logger,
+		policyName:        TestPolicy,
+		policyDir:         PolicyDir,
+		restartBackoff:    10 * time.Millisecond,
+		restartBackoffMax: 100 * time.Millisecond,
+	}
+	if err := runner.Configure(validPolicy()); err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := runner.Start(ctx, cancel); err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+	defer runner.Stop(ctx)
+
+	// Act
+	runner.mu.RLock()
+	pid := runner.cmd.Process.Pid
+	err := runner.cmd.Process.Kill()
+	runner.mu.RUnlock()
+	if err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+
+	// Assert
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s := runner.GetStatus()
+		if s.RestartCount == 1 && s.Status == running {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	s := runner.GetStatus()
+	if s.RestartCount != 1 {
+		t.Fatalf("Expected restart count to be 1, but got %d", s.RestartCount)
+	}
+	if s.Status != running {
+		t.Errorf("Expected status to be running, but got %v", s.StatusText)
+	}
+	if s.LastRestartTS.IsZero() {
+		t.Errorf("Expected last restart time to be set")
+	}
+	runner.mu.RLock()
+	newPid := runner.cmd.Process.Pid
+	runner.mu.RUnlock()
+	if newPid == pid {
+		t.Errorf("Expected a new collector process, but pid %d is unchanged", pid)
+	}
+	var statuses []string
+	for _, tr := range runner.Transitions() {
+		statuses = append(statuses, tr.To)
+	}
+	want := []string{"starting", "running", "degraded", "starting", "running"}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("Expected transitions %v, but got %v", want, statuses)
+	}
+}
+
+func TestRunnerTransitions(t *testing.T) {
+	// Arrange
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: false}))
+	runner := NewRunner(logger, TestPolicy, PolicyDir, &config.Config{})
+
+	// Act
+	runner.setStatus(starting, "collector launched")
+	runner.setStatus(running, "collector is ready")
+	runner.setError(degraded, errors.New("collector exited"))
+	runner.setStatus(stopped, "stop requested")
+	runner.setError(degraded, errors.New("late collector error"))
+	runner.setStatus(running, "late ready log")
+
+	// Assert
+	s := runner.GetStatus()
+	if s.Status != stopped {
+		t.Errorf("Expected status to stay stopped, but got %v", s.StatusText)
+	}
+	if s.LastError != "collector exited" {
+		t.Errorf("Expected the late error to be ignored, but got %q", s.LastError)
+	}
+	history := runner.Transitions()
+	if len(history) != 4 {
+		t.Fatalf("Expected 4 transitions, but got %+v", history)
+	}
+	if tr := history[2]; tr.From != "running" || tr.To != "degraded" || tr.Reason != "collector exited" || tr.Time.IsZero() {
+		t.Errorf("Expected the collector exit to be recorded, but got %+v", tr)
+	}
+	for i := 0; i < maxTransitions; i++ {
+		runner.state.Status = starting
+		runner.setStatus(running, "")
+	}
+	if n := len(runner.Transitions()); n != maxTransitions {
+		t.Errorf("Expected %d transitions to be kept, but got %d", maxTransitions, n)
+	}
+}
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to status
+		want     bool
+	}{
+		{pending, starting, true},
+		{starting, running, true},
+		{running, degraded, true},
+		{degraded, crashLoop, true},
+		{crashLoop, starting, true},
+		{running, stopping, true},
+		{stopping, stopped, true},
+		{failed, stopped, true},
+		{stopped, degraded, false},
+		{stopped, running, false},
+		{stopping, degraded, false},
+		{pending, running, false},
+		{failed, running, false},
+	}
+	for _, tt := range tests {
+		if got := canTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("Expected transition from %s to %s allowed to be %v, but got %v", mapStatus[tt.from], mapStatus[tt.to], tt.want, got)
+		}
+	}
+}
+
+func TestRunnerCrashLoop(t *testing.T) {
+	// Arrange
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: false}))
+	runner := &Runner{
+		logger:            logger,
+		policyName:        TestPolicy,
+		policyDir:         PolicyDir,
+		restartBackoff:    10 * time.Millisecond,
+		restartBackoffMax: 100 * time.Millisecond,
+		maxRestarts:       1,
+		restartWindow:     time.Minute,
+	}
+	if err := runner.Configure(validPolicy()); err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := runner.Start(ctx, cancel); err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+	defer runner.Stop(ctx)
+
+	if err := runner.Reset(); err != ErrNotCrashLooping {
+		t.Errorf("Expected %v, but got %v", ErrNotCrashLooping, err)
+	}
+
+	// Act
+	killCollector(t, runner)
+	waitForStatus(t, runner, running, 1)
+	killCollector(t, runner)
+
+	// Assert
+	waitForStatus(t, runner, crashLoop, 1)
+
+	// Act
+	if err := runner.Reset(); err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+
+	// Assert
+	waitForStatus(t, runner, running, 2)
+}
+
+func killCollector(t *testing.T, r *Runner) {
+	t.Helper()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if err := r.cmd.Process.Kill(); err != nil {
+		t.Fatalf(ErrorMessage, err)
+	}
+}
+
+func waitForStatus(t *testing.T, r *Runner, want status, restarts int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s := r.GetStatus()
+		if s.Status == want && s.RestartCount == restarts {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	s := r.GetStatus()
+	t.Fatalf("Expected status %v with %d restarts, but got %v with %d restarts", mapStatus[want], restarts, s.StatusText, s.RestartCount)
+}
+
+func TestRunnerBackoff(t *testing.T) {
+	runner := &Runner{
+		restartBackoff:    time.Second,
+		restartBackoffMax: 10 * time.Second,
+	}
+
+	cases := map[int]time.Duration{
+		0: time.Second,
+		1: 2 * time.Second,
+		2: 4 * time.Second,
+		3: 8 * time.Second,
+		4: 10 * time.Second,
+		9: 10 * time.Second,
+	}
+	for attempt, want := range cases {
+		if got := runner.backoff(attempt); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+
+	runner.restartJitter = 0.5
+	for i := 0; i < 100; i++ {
+		got := runner.backoff(1)
+		if got < time.Second || got > 3*time.Second {
+			t.Fatalf("backoff(1) with jitter = %v, want within [1s, 3s]", got)
+		}
+	}
+}
+
+func validPolicy() *config.Policy {
+	return &config.Policy{
+		Receivers: map[string]interface{}{
+			"otlp": map[string]interface{}{
+				"protocols": map[string]interface{}{
+					"grpc": map[string]interface{}{"endpoint": "localhost:0"},
+				},
+			},
+		},
+		Exporters: map[string]interface{}{
+			"debug": nil,
+		},
+		Service: map[string]interface{}{
+			"pipelines": map[string]interface{}{
+				"metrics": map[string]interface{}{
+					"receivers": []string{"otlp"},
+					"exporters": []string{"debug"},
+				},
+			},
+		},
 	}
 }
 
@@ -182,7 +602,7 @@ func TestRunnerGetCapabilities(t *testing.T) {
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: false}))
 
 	// Act
-	caps, err := GetCapabilities(logger)
+	caps, err := NewCollector("").GetCapabilities(logger)
 	if err != nil {
 		t.Errorf(ErrorMessage, err)
 	}
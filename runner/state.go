@@ -0,0 +1,129 @@
+package runner
+
+import (
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxTransitions is the number of status transitions kept per runner
+const maxTransitions = 100
+
+type status int
+
+const (
+	// pending runners have been configured but not started
+	pending status = iota
+	// starting runners have launched a collector that is not ready yet
+	starting
+	// running runners have a ready collector
+	running
+	// degraded runners have a collector that exited unexpectedly and is
+	// being restarted
+	degraded
+	// crashLoop runners have suspended restarts until they are reset
+	crashLoop
+	// stopping runners are waiting for their collector to drain and exit
+	stopping
+	// stopped runners are done and never change status again
+	stopped
+	// failed runners could not start their collector
+	failed
+)
+
+var mapStatus = map[status]string{
+	pending:   "pending",
+	starting:  "starting",
+	running:   "running",
+	degraded:  "degraded",
+	crashLoop: "crash_loop",
+	stopping:  "stopping",
+	stopped:   "stopped",
+	failed:    "failed",
+}
+
+// transitions lists the statuses a runner may go to from each status
+var transitions = map[status][]status{
+	pending:   {starting, failed, stopped},
+	starting:  {running, degraded, failed, stopping, stopped},
+	running:   {degraded, stopping, stopped},
+	degraded:  {starting, crashLoop, stopping, stopped},
+	crashLoop: {starting, degraded, stopping, stopped},
+	stopping:  {stopped},
+	failed:    {stopping, stopped},
+}
+
+func canTransition(from status, to status) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition is a change of the status of a runner
+type Transition struct {
+	Time   time.Time `json:"time"`
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+func (r *Runner) setStatus(s status, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transition(s, reason)
+}
+
+func (r *Runner) setError(s status, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.transition(s, err.Error()) {
+		r.state.LastError = err.Error()
+	}
+}
+
+// transition moves the runner to status s, records the change in the runner
+// history and as an event of the span of the operation in progress, and
+// reports whether the runner is in status s. Transitions that are not allowed
+// from the current status, such as a collector error reported after the runner
+// was stopped, are ignored. r.mu must be held.
+func (r *Runner) transition(s status, reason string) bool {
+	from := r.state.Status
+	if from == s {
+		return true
+	}
+	if !canTransition(from, s) {
+		r.logger.Debug("ignored runner status transition", slog.String("policy", r.policyName),
+			slog.String("from", mapStatus[from]), slog.String("to", mapStatus[s]), slog.String("reason", reason))
+		return false
+	}
+	if r.span != nil {
+		attrs := []attribute.KeyValue{
+			attribute.String("from", mapStatus[from]),
+			attribute.String("to", mapStatus[s]),
+		}
+		if reason != "" {
+			attrs = append(attrs, attribute.String("reason", reason))
+		}
+		r.span.AddEvent("state_transition", trace.WithAttributes(attrs...))
+	}
+	r.history = append(r.history, Transition{Time: time.Now(), From: mapStatus[from], To: mapStatus[s], Reason: reason})
+	if len(r.history) > maxTransitions {
+		r.history = append([]Transition(nil), r.history[len(r.history)-maxTransitions:]...)
+	}
+	r.state.Status = s
+	r.state.StatusText = mapStatus[s]
+	return true
+}
+
+// Transitions returns the status transitions of the runner, oldest first,
+// including those of the runners it adopted
+func (r *Runner) Transitions() []Transition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Transition{}, r.history...)
+}
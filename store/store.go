@@ -0,0 +1,107 @@
+package store
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/netboxlabs/opentelemetry-infinity/config"
+)
+
+const recordExt = ".yaml"
+
+// Record represents an applied policy and its metadata
+type Record struct {
+	Policy    config.Policy `yaml:"policy"`
+	UpdatedAt time.Time     `yaml:"updated_at"`
+	ChangedBy string        `yaml:"changed_by,omitempty"`
+}
+
+// Store persists applied policies as one file per policy in a directory, so
+// that they can be restored when otlpinf restarts
+type Store struct {
+	dir string
+}
+
+// New creates a store in the given directory, creating it if needed
+func New(dataDir string) (*Store, error) {
+	dir := filepath.Join(dataDir, "policies")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Save records a policy, replacing any previous record of it. The record is
+// written to a temporary file first so that a crash never leaves it partial.
+func (s *Store) Save(name string, rec Record) error {
+	b, err := yaml.Marshal(&rec)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(s.dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	if _, err = f.Write(b); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path(name))
+}
+
+// Delete removes the record of a policy
+func (s *Store) Delete(name string) error {
+	err := os.Remove(s.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Load returns every recorded policy by name
+func (s *Store) Load() (map[string]Record, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	records := make(map[string]Record)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), recordExt) {
+			continue
+		}
+		name, err := url.PathUnescape(strings.TrimSuffix(e.Name(), recordExt))
+		if err != nil {
+			return nil, err
+		}
+		b, err := os.ReadFile(filepath.Join(s.dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var rec Record
+		if err = yaml.Unmarshal(b, &rec); err != nil {
+			return nil, errors.New("policy record " + e.Name() + ": " + err.Error())
+		}
+		records[name] = rec
+	}
+	return records, nil
+}
+
+// path returns the file of a policy record. Policy names are escaped so that
+// they cannot point outside of the store directory.
+func (s *Store) path(name string) string {
+	return filepath.Join(s.dir, url.PathEscape(name)+recordExt)
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/netboxlabs/opentelemetry-infinity/config"
+)
+
+// Saved records are loaded back by policy name, including names that are not valid file names.
+func TestStoreSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	s, err := New(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rec := Record{
+		Policy:    config.Policy{Exporters: map[string]interface{}{"debug": nil}, Otlpinf: config.PolicyOptions{DrainTimeout: time.Second}},
+		UpdatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		ChangedBy: "10.0.0.1",
+	}
+	for _, name := range []string{"p1", "../escape", "a/b"} {
+		if err = s.Save(name, rec); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+	}
+	rec.ChangedBy = "10.0.0.2"
+	if err = s.Save("p1", rec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reopened, err := New(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records, err := reopened.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %v", records)
+	}
+	got := records["p1"]
+	if got.ChangedBy != "10.0.0.2" || !got.UpdatedAt.Equal(rec.UpdatedAt) || got.Policy.Otlpinf.DrainTimeout != time.Second {
+		t.Errorf("unexpected record %+v", got)
+	}
+	if _, ok := records["../escape"]; !ok {
+		t.Errorf("expected ../escape to be stored, got %v", records)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "escape.yaml")); !os.IsNotExist(err) {
+		t.Errorf("expected the record to stay inside the store directory")
+	}
+}
+
+// Deleted records are not loaded again and deleting a missing record succeeds.
+func TestStoreDelete(t *testing.T) {
+	s, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = s.Save("p1", Record{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = s.Delete("p1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = s.Delete("p1"); err != nil {
+		t.Errorf("expected deleting a missing record to succeed, got %v", err)
+	}
+	records, err := s.Load()
+	if err != nil || len(records) != 0 {
+		t.Errorf("expected no records, got %v, %v", records, err)
+	}
+}